@@ -0,0 +1,164 @@
+// Package inview is a typed client for the InView public API.
+package inview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// timeLayout is the layout InView expects for dateFrom/dateTo.
+const timeLayout = "2006-01-02T15:04:05"
+
+// Client talks to the InView public API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	retry      RetryPolicy
+	location   *time.Location
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithRetryPolicy sets the policy used to retry transient failures.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithLocation sets the timezone the InView server logs in. Query ranges
+// are sent as wall-clock times in loc. The default is UTC.
+func WithLocation(loc *time.Location) Option {
+	return func(c *Client) {
+		c.location = loc
+	}
+}
+
+// New creates a Client for baseURL authenticating with apiKey.
+// A nil httpClient falls back to a plain http.Client.
+func New(baseURL, apiKey string, httpClient *http.Client, opts ...Option) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	c := &Client{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		httpClient: httpClient,
+		retry:      DefaultRetryPolicy,
+		location:   time.UTC,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// formatTime formats t as a wall-clock time in the server's timezone.
+func (c *Client) formatTime(t time.Time) string {
+	return t.In(c.location).Format(timeLayout)
+}
+
+// get issues a GET request against path and decodes the JSON body into out.
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	body, err := c.do(ctx, http.MethodGet, u)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+	return nil
+}
+
+// do performs the request, retrying transient failures of idempotent methods
+// according to the client's retry policy, and returns the 200 response body.
+func (c *Client) do(ctx context.Context, method, u string) ([]byte, error) {
+	attempts := 1
+	if isIdempotent(method) && c.retry.MaxAttempts > 1 {
+		attempts = c.retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		body, retryAfter, err := c.doOnce(ctx, method, u)
+		if err == nil {
+			return body, nil
+		}
+		if attempt >= attempts || !c.retryable(err) {
+			return nil, err
+		}
+
+		delay, ok := c.retry.backoff(attempt, retryAfter)
+		if !ok {
+			log.DefaultLogger.Debug("InView request failed, Retry-After exceeds the max delay", "url", u, "retryAfter", retryAfter, "error", err)
+			return nil, err
+		}
+		log.DefaultLogger.Debug("InView request failed, retrying", "url", u, "attempt", attempt, "delay", delay, "error", err)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		recordRetry(ctx)
+	}
+}
+
+// doOnce performs a single attempt. On a non-200 response it also returns the
+// server's Retry-After hint.
+func (c *Client) doOnce(ctx context.Context, method, u string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	log.DefaultLogger.Debug("InView request", "url", u)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, 0, ctxErr
+		}
+		return nil, 0, fmt.Errorf("%w: %w", ErrRequestFailed, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, 0, ctxErr
+		}
+		return nil, 0, fmt.Errorf("%w: %w", ErrRequestFailed, err)
+	}
+
+	log.DefaultLogger.Debug("InView response", "url", u, "statusCode", resp.StatusCode, "bytes", len(body))
+
+	if resp.StatusCode != http.StatusOK {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, retryAfter, newAPIError(resp.StatusCode, body)
+	}
+	return body, 0, nil
+}
+
+// retryable reports whether err is a transient failure worth another attempt.
+func (c *Client) retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return c.retry.retryableStatus(apiErr.StatusCode)
+	}
+	return errors.Is(err, ErrRequestFailed)
+}
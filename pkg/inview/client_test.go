@@ -0,0 +1,240 @@
+package inview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetAlarms(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/public/alarms" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "key" {
+			t.Errorf("Authorization = %q, want %q", got, "key")
+		}
+		q := r.URL.Query()
+		if got := q.Get("dateFrom"); got != "2024-01-02T03:04:05" {
+			t.Errorf("dateFrom = %q", got)
+		}
+		if got := q.Get("varId"); got != "1,2" {
+			t.Errorf("varId = %q", got)
+		}
+		if got := q.Get("pageSize"); got != "25" {
+			t.Errorf("pageSize = %q", got)
+		}
+		_, _ = w.Write([]byte(`[{"iwsAlarmDescription":"High level","iwsAlarmActivationTime":"2024-01-02T03:04:05"}]`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client())
+	alarms, err := c.GetAlarms(context.Background(), AlarmsOptions{
+		From:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		To:          time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC),
+		VariableIDs: []int{1, 2},
+		PageSize:    25,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alarms) != 1 || alarms[0].IwsAlarmDescription != "High level" {
+		t.Fatalf("unexpected alarms %+v", alarms)
+	}
+}
+
+func TestWithLocation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("dateFrom"); got != "2024-07-01T14:00:00" {
+			t.Errorf("dateFrom = %q, want plant-local time", got)
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	loc, err := time.LoadLocation("Europe/Vienna")
+	if err != nil {
+		t.Skip(err)
+	}
+	c := New(srv.URL, "key", srv.Client(), WithLocation(loc))
+	_, err = c.GetHistory(context.Background(), HistoryOptions{
+		From: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 7, 2, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client())
+	_, err := c.ListConnections(context.Background(), ConnectionsOptions{})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("StatusCode = %d", apiErr.StatusCode)
+	}
+}
+
+func TestInvalidResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`not json`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client())
+	_, err := c.GetHistory(context.Background(), HistoryOptions{})
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("expected ErrInvalidResponse, got %v", err)
+	}
+}
+
+func TestCanceledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client())
+	_, err := c.GetEvents(ctx, EventsOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client(), WithRetryPolicy(RetryPolicy{
+		MaxAttempts:       3,
+		BaseDelay:         time.Millisecond,
+		RetryableStatuses: []int{http.StatusServiceUnavailable},
+	}))
+	stats := &RetryStats{}
+	_, err := c.GetAlarms(WithRetryStats(context.Background(), stats), AlarmsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || stats.Retries() != 1 {
+		t.Fatalf("calls = %d, retries = %d", calls, stats.Retries())
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	var calls []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, time.Now())
+		if len(calls) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client(), WithRetryPolicy(RetryPolicy{
+		MaxAttempts:       3,
+		BaseDelay:         time.Millisecond,
+		MaxDelay:          5 * time.Second,
+		RetryableStatuses: []int{http.StatusServiceUnavailable},
+	}))
+	if _, err := c.GetAlarms(context.Background(), AlarmsOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("calls = %d, want 2", len(calls))
+	}
+	if d := calls[1].Sub(calls[0]); d < time.Second {
+		t.Errorf("retried after %s, before the server's Retry-After of 1s", d)
+	}
+}
+
+func TestRetryAfterBeyondMaxDelay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client(), WithRetryPolicy(RetryPolicy{
+		MaxAttempts:       3,
+		BaseDelay:         time.Millisecond,
+		MaxDelay:          time.Second,
+		RetryableStatuses: []int{http.StatusServiceUnavailable},
+	}))
+	_, err := c.GetAlarms(context.Background(), AlarmsOptions{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got %v, want the 503", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client())
+	if _, err := c.GetAlarms(context.Background(), AlarmsOptions{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"title":"One or more validation errors occurred.","status":400,"traceId":"00-abc","errors":{"dateFrom":["The value is not valid."]}}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key", srv.Client())
+	_, err := c.GetHistory(context.Background(), HistoryOptions{})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.TraceID != "00-abc" || len(apiErr.Errors["dateFrom"]) != 1 {
+		t.Fatalf("unexpected APIError %+v", apiErr)
+	}
+	want := "InView API error (400): One or more validation errors occurred.; dateFrom: The value is not valid. (trace id 00-abc)"
+	if apiErr.Error() != want {
+		t.Fatalf("Error() = %q, want %q", apiErr.Error(), want)
+	}
+}
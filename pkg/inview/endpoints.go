@@ -0,0 +1,156 @@
+package inview
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AlarmsOptions filters a GetAlarms call.
+type AlarmsOptions struct {
+	From           time.Time
+	To             time.Time
+	VariableIDs    []int
+	LocationPrefix string
+	PageIndex      int
+	PageSize       int
+}
+
+// EventsOptions filters a GetEvents call.
+type EventsOptions struct {
+	From           time.Time
+	To             time.Time
+	VariableIDs    []int
+	LocationPrefix string
+	OpcTags        string
+	PageIndex      int
+	PageSize       int
+}
+
+// HistoryOptions filters a GetHistory or GetLiveValues call.
+type HistoryOptions struct {
+	From        time.Time
+	To          time.Time
+	VariableIDs []int
+}
+
+// VariablesOptions filters a ListVariables call.
+type VariablesOptions struct {
+	Page            int
+	ItemsPerPage    int
+	SkipFilterConns bool
+	ConnectionID    int
+	LikeParam       string
+	SkipPagination  bool
+}
+
+// ConnectionsOptions filters a ListConnections call.
+type ConnectionsOptions struct {
+	PageIndex            int
+	PageSize             int
+	SkipConnectionFilter bool
+	SearchText           string
+}
+
+// GetAlarms returns one page of the alarms log.
+func (c *Client) GetAlarms(ctx context.Context, opts AlarmsOptions) ([]AlarmLog, error) {
+	q := url.Values{}
+	q.Set("dateFrom", c.formatTime(opts.From))
+	q.Set("dateTo", c.formatTime(opts.To))
+	q.Set("varId", joinIDs(opts.VariableIDs))
+	q.Set("locationPrefix", opts.LocationPrefix)
+	q.Set("pageIndex", strconv.Itoa(opts.PageIndex))
+	q.Set("pageSize", strconv.Itoa(opts.PageSize))
+
+	var out []AlarmLog
+	if err := c.get(ctx, "/api/public/alarms", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetEvents returns one page of the events log.
+func (c *Client) GetEvents(ctx context.Context, opts EventsOptions) ([]EventLog, error) {
+	q := url.Values{}
+	q.Set("dateFrom", c.formatTime(opts.From))
+	q.Set("dateTo", c.formatTime(opts.To))
+	q.Set("varId", joinIDs(opts.VariableIDs))
+	q.Set("locationPrefix", opts.LocationPrefix)
+	q.Set("opcTags", opts.OpcTags)
+	q.Set("pageIndex", strconv.Itoa(opts.PageIndex))
+	q.Set("pageSize", strconv.Itoa(opts.PageSize))
+
+	var out []EventLog
+	if err := c.get(ctx, "/api/public/events", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetHistory returns the logged values of the requested variables.
+func (c *Client) GetHistory(ctx context.Context, opts HistoryOptions) ([]RawLiveValue, error) {
+	return getHistory[RawLiveValue](ctx, c, opts)
+}
+
+// GetLiveValues returns the logged values of the requested variables like
+// GetHistory, but keeps each value as decoded from JSON so that boolean and
+// string variables can be read too.
+func (c *Client) GetLiveValues(ctx context.Context, opts HistoryOptions) ([]LiveValue, error) {
+	return getHistory[LiveValue](ctx, c, opts)
+}
+
+// getHistory queries the logged values and decodes them as T.
+func getHistory[T any](ctx context.Context, c *Client, opts HistoryOptions) ([]T, error) {
+	q := url.Values{}
+	q.Set("dateFrom", c.formatTime(opts.From))
+	q.Set("dateTo", c.formatTime(opts.To))
+	q.Set("varId", joinIDs(opts.VariableIDs))
+
+	var out []T
+	if err := c.get(ctx, "/api/public/variables/getHistoryLoggedValuesV2", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListVariables returns the variables catalog.
+func (c *Client) ListVariables(ctx context.Context, opts VariablesOptions) ([]Variables, error) {
+	q := url.Values{}
+	q.Set("page", strconv.Itoa(opts.Page))
+	q.Set("itemsPerPage", strconv.Itoa(opts.ItemsPerPage))
+	q.Set("skipFilterConns", strconv.FormatBool(opts.SkipFilterConns))
+	q.Set("connId", strconv.Itoa(opts.ConnectionID))
+	q.Set("likeParam", opts.LikeParam)
+	q.Set("skipPagination", strconv.FormatBool(opts.SkipPagination))
+
+	var out []Variables
+	if err := c.get(ctx, "/api/public/variables-dto", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListConnections returns the connections catalog.
+func (c *Client) ListConnections(ctx context.Context, opts ConnectionsOptions) ([]Connections, error) {
+	q := url.Values{}
+	q.Set("pageIndex", strconv.Itoa(opts.PageIndex))
+	q.Set("pageSize", strconv.Itoa(opts.PageSize))
+	q.Set("skipConnectionFilter", strconv.FormatBool(opts.SkipConnectionFilter))
+	q.Set("searchText", opts.SearchText)
+
+	var out []Connections
+	if err := c.get(ctx, "/api/public/connections", q, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,78 @@
+package inview
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrRequestFailed is returned when InView could not be reached.
+	ErrRequestFailed = errors.New("API request failed")
+	// ErrInvalidResponse is returned when an InView response could not be decoded.
+	ErrInvalidResponse = errors.New("invalid API response")
+)
+
+// APIError is returned when InView answers with a non-200 status. When the
+// body is an RFC 7807 problem details document its fields are decoded.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Title      string
+	// Errors holds per-field validation messages keyed by field name.
+	Errors  map[string][]string
+	TraceID string
+	Body    []byte
+}
+
+// problemDetails is the error body InView sends for failed requests.
+type problemDetails struct {
+	Errors  map[string][]string `json:"errors"`
+	Type    string              `json:"type"`
+	Title   string              `json:"title"`
+	Status  int                 `json:"status"`
+	TraceId string              `json:"traceId"`
+}
+
+func newAPIError(status int, body []byte) *APIError {
+	e := &APIError{StatusCode: status, Body: body}
+
+	var p problemDetails
+	if err := json.Unmarshal(body, &p); err == nil {
+		e.Type = p.Type
+		e.Title = p.Title
+		e.Errors = p.Errors
+		e.TraceID = p.TraceId
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "InView API error (%d)", e.StatusCode)
+
+	msg := e.Title
+	if msg == "" && len(e.Errors) == 0 {
+		msg = strings.TrimSpace(string(e.Body))
+	}
+	if msg != "" {
+		b.WriteString(": ")
+		b.WriteString(msg)
+	}
+
+	fields := make([]string, 0, len(e.Errors))
+	for f := range e.Errors {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		fmt.Fprintf(&b, "; %s: %s", f, strings.Join(e.Errors[f], ", "))
+	}
+
+	if e.TraceID != "" {
+		fmt.Fprintf(&b, " (trace id %s)", e.TraceID)
+	}
+	return b.String()
+}
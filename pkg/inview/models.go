@@ -0,0 +1,49 @@
+package inview
+
+// Variables is a single entry of the variables-dto catalog. The engineering
+// metadata is optional and only set for variables configured with it.
+type Variables struct {
+	ID           int      `json:"id"`
+	VariableName string   `json:"variableName"`
+	Unit         string   `json:"unit,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Decimals     *int     `json:"decimals,omitempty"`
+	Min          *float64 `json:"minValue,omitempty"`
+	Max          *float64 `json:"maxValue,omitempty"`
+}
+
+// Connections is a single entry of the connections catalog.
+type Connections struct {
+	ID             int    `json:"id"`
+	ConnectionName string `json:"name"`
+}
+
+// RawLiveValue is a single logged sample returned by getHistoryLoggedValuesV2.
+type RawLiveValue struct {
+	VariableId int     `json:"VariableId"`
+	Value      float64 `json:"Value"`
+	Timestamp  string  `json:"timestamp"`
+	Quality    int     `json:"quality"`
+}
+
+// LiveValue is a logged sample whose value may be a number, a boolean or a
+// string.
+type LiveValue struct {
+	VariableId int    `json:"VariableId"`
+	Value      any    `json:"Value"`
+	Timestamp  string `json:"timestamp"`
+	Quality    int    `json:"quality"`
+}
+
+// AlarmLog is a single row of the alarms log.
+type AlarmLog struct {
+	IwsAlarmDescription     string `json:"iwsAlarmDescription"`
+	IwsAlarmActivationTime  string `json:"iwsAlarmActivationTime"`
+	IwsAlarmTerminationTime string `json:"iwsAlarmTerminationTime"`
+}
+
+// EventLog is a single row of the events log.
+type EventLog struct {
+	IwsEventDescription string `json:"iwsEventDescription"`
+	IwsEventTimestamp   string `json:"iwsEventTimestamp"`
+}
@@ -0,0 +1,121 @@
+package inview
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy controls how the client retries transient failures. Only
+// idempotent requests are ever retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles on every
+	// further attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the computed backoff. A server asking for a longer
+	// Retry-After is not retried at all.
+	MaxDelay time.Duration
+	// Jitter randomizes each delay by up to this fraction, e.g. 0.2 for ±20%.
+	Jitter float64
+	// RetryableStatuses lists the HTTP statuses that are worth retrying.
+	RetryableStatuses []int
+}
+
+// DefaultRetryPolicy is used when no policy is configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	BaseDelay:         500 * time.Millisecond,
+	MaxDelay:          30 * time.Second,
+	Jitter:            0.2,
+	RetryableStatuses: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+}
+
+func (p RetryPolicy) retryableStatus(status int) bool {
+	return slices.Contains(p.RetryableStatuses, status)
+}
+
+// backoff returns the delay before retry number n (starting at 1). A positive
+// retryAfter from the server takes precedence over the computed backoff. It
+// reports false when retryAfter exceeds MaxDelay, since retrying any sooner
+// would ignore the server.
+func (p RetryPolicy) backoff(n int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		return retryAfter, p.MaxDelay <= 0 || retryAfter <= p.MaxDelay
+	}
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(n-1)))
+	if p.Jitter > 0 {
+		delay = time.Duration(float64(delay) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, true
+}
+
+// parseRetryAfter decodes a Retry-After header given either in seconds or as
+// an HTTP date. It returns zero when the header is absent or invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// RetryStats counts the retries performed for the requests made with a
+// context returned by WithRetryStats.
+type RetryStats struct {
+	retries atomic.Int64
+}
+
+// Retries returns the number of retries recorded so far.
+func (s *RetryStats) Retries() int {
+	return int(s.retries.Load())
+}
+
+type retryStatsKey struct{}
+
+// WithRetryStats returns a context that records retries into stats.
+func WithRetryStats(ctx context.Context, stats *RetryStats) context.Context {
+	return context.WithValue(ctx, retryStatsKey{}, stats)
+}
+
+func recordRetry(ctx context.Context) {
+	if stats, ok := ctx.Value(retryStatsKey{}).(*RetryStats); ok {
+		stats.retries.Add(1)
+	}
+}
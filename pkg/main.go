@@ -2,8 +2,9 @@ package main
 
 import (
 	"os"
+	// Embed the timezone database, which minimal plugin hosts may lack.
+	_ "time/tzdata"
 
-	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/init/in-view/pkg/plugin"
@@ -15,31 +16,10 @@ func main() {
 	// to exit by itself using os.Exit. Manage automatically manages life cycle
 	// of datasource instances. It accepts datasource instance factory as first
 	// argument. This factory will be automatically called on incoming request
-	// from Grafana to create different instances of SampleDatasource (per datasource
+	// from Grafana to create different instances of Datasource (per datasource
 	// ID). When datasource configuration changed Dispose method will be called and
-	// new datasource instance created using NewSampleDatasource factory.
-
-	ds := &plugin.Datasource{} 
-
- 
-
-		if err := backend.Serve(backend.ServeOpts{ 
-
-		CheckHealthHandler:  ds, 
-
-		QueryDataHandler:    ds, 
-
-		CallResourceHandler: ds, // ✅ Register custom endpoint support 
-
-		}); err != nil { 
-
-		log.DefaultLogger.Error(err.Error()) 
-
-		os.Exit(1) 
-
-		} 
-
-	if err := datasource.Manage("init-inview-datasource", plugin.NewDatasource, datasource.ManageOpts{}); err != nil {
+	// new datasource instance created using NewDatasource factory.
+	if err := datasource.Manage("inittechnologies-inview-datasource", plugin.NewDatasource, datasource.ManageOpts{}); err != nil {
 		log.DefaultLogger.Error(err.Error())
 		os.Exit(1)
 	}
@@ -3,25 +3,170 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/init/in-view/pkg/inview"
 )
 
+// DefaultBaseUrl is the InView cloud API used when no base URL is configured.
+const DefaultBaseUrl = "https://cloud.oilfield-monitor.com"
+
+// DefaultMaxRows caps alarm and event queries that fetch all pages.
+const DefaultMaxRows = 10000
+
+// DefaultHistoryChunk is the window size long history queries are split into.
+const DefaultHistoryChunk = "1d"
+
+// DefaultHistoryChunkConcurrency bounds the history windows fetched at once.
+const DefaultHistoryChunkConcurrency = 4
+
+// DefaultCatalogCacheTTL is how long the variable catalog is cached.
+const DefaultCatalogCacheTTL = "10m"
+
+// DefaultMaxConcurrentQueries bounds the queries a datasource instance runs
+// at once.
+const DefaultMaxConcurrentQueries = 8
+
 type PluginSettings struct {
-	BaseUrl string                `json:"baseUrl"` 
-	Path    string                `json:"path"`
+	BaseUrl string `json:"baseUrl"`
+	Path    string `json:"path"`
+
+	// Timeout is the overall request timeout in seconds.
+	Timeout int `json:"timeout"`
+	// DialTimeout is the TCP connect timeout in seconds.
+	DialTimeout int `json:"dialTimeout"`
+	// TLSHandshakeTimeout is the TLS handshake timeout in seconds.
+	TLSHandshakeTimeout int `json:"tlsHandshakeTimeout"`
+
+	// RetryMaxAttempts is the total number of attempts per InView request.
+	// 1 disables retries.
+	RetryMaxAttempts int `json:"retryMaxAttempts"`
+	// RetryBaseDelayMs is the delay before the first retry in milliseconds.
+	RetryBaseDelayMs int `json:"retryBaseDelayMs"`
+	// RetryJitter randomizes retry delays by up to this fraction.
+	RetryJitter *float64 `json:"retryJitter"`
+	// RetryStatuses lists the HTTP statuses that are retried.
+	RetryStatuses []int `json:"retryStatuses"`
+
+	// MaxRows caps alarm and event queries that fetch all pages.
+	MaxRows int `json:"maxRows"`
+
+	// MaxConcurrentQueries bounds the queries a datasource instance runs at
+	// once.
+	MaxConcurrentQueries int `json:"maxConcurrentQueries"`
+
+	// HistoryChunk is the window size, e.g. "12h" or "1d", long history
+	// queries are split into.
+	HistoryChunk         string        `json:"historyChunk"`
+	HistoryChunkDuration time.Duration `json:"-"`
+	// HistoryChunkConcurrency bounds the history windows fetched at once.
+	HistoryChunkConcurrency int `json:"historyChunkConcurrency"`
+
+	// CatalogCacheTTL is how long the variable catalog, which supplies
+	// units and display names, is cached, e.g. "10m".
+	CatalogCacheTTL         string        `json:"catalogCacheTtl"`
+	CatalogCacheTTLDuration time.Duration `json:"-"`
+
+	// Timezone is the IANA name of the timezone the InView server logs in,
+	// e.g. "Europe/Vienna". Defaults to UTC.
+	Timezone string         `json:"timezone"`
+	Location *time.Location `json:"-"`
+
 	Secrets *SecretPluginSettings `json:"-"`
 }
 
+// TimeoutOptions returns the HTTP client timeouts, falling back to the SDK
+// defaults for anything left unset.
+func (s *PluginSettings) TimeoutOptions() httpclient.TimeoutOptions {
+	opts := httpclient.DefaultTimeoutOptions
+	if s.Timeout > 0 {
+		opts.Timeout = time.Duration(s.Timeout) * time.Second
+	}
+	if s.DialTimeout > 0 {
+		opts.DialTimeout = time.Duration(s.DialTimeout) * time.Second
+	}
+	if s.TLSHandshakeTimeout > 0 {
+		opts.TLSHandshakeTimeout = time.Duration(s.TLSHandshakeTimeout) * time.Second
+	}
+	return opts
+}
+
+// RetryPolicy returns the InView retry policy, falling back to
+// inview.DefaultRetryPolicy for anything left unset.
+func (s *PluginSettings) RetryPolicy() inview.RetryPolicy {
+	p := inview.DefaultRetryPolicy
+	if s.RetryMaxAttempts > 0 {
+		p.MaxAttempts = s.RetryMaxAttempts
+	}
+	if s.RetryBaseDelayMs > 0 {
+		p.BaseDelay = time.Duration(s.RetryBaseDelayMs) * time.Millisecond
+	}
+	if s.RetryJitter != nil {
+		p.Jitter = *s.RetryJitter
+	}
+	if len(s.RetryStatuses) > 0 {
+		p.RetryableStatuses = s.RetryStatuses
+	}
+	return p
+}
+
 type SecretPluginSettings struct {
 	ApiKey string `json:"apiKey"`
 }
 
 func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*PluginSettings, error) {
 	settings := PluginSettings{}
-	err := json.Unmarshal(source.JSONData, &settings)
+	if len(source.JSONData) > 0 {
+		err := json.Unmarshal(source.JSONData, &settings)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal PluginSettings json: %w", err)
+		}
+	}
+
+	baseUrl, err := normalizeBaseUrl(settings.BaseUrl)
 	if err != nil {
-		return nil, fmt.Errorf("could not unmarshal PluginSettings json: %w", err)
+		return nil, err
+	}
+	settings.BaseUrl = baseUrl
+
+	if settings.RetryJitter != nil && (*settings.RetryJitter < 0 || *settings.RetryJitter > 1) {
+		return nil, fmt.Errorf("invalid retry jitter %v: must be between 0 and 1", *settings.RetryJitter)
+	}
+
+	if settings.MaxRows <= 0 {
+		settings.MaxRows = DefaultMaxRows
+	}
+	if settings.MaxConcurrentQueries <= 0 {
+		settings.MaxConcurrentQueries = DefaultMaxConcurrentQueries
+	}
+	if settings.HistoryChunk == "" {
+		settings.HistoryChunk = DefaultHistoryChunk
+	}
+	settings.HistoryChunkDuration, err = ParseDuration(settings.HistoryChunk)
+	if err != nil || settings.HistoryChunkDuration <= 0 {
+		return nil, fmt.Errorf("invalid history chunk %q", settings.HistoryChunk)
+	}
+	if settings.HistoryChunkConcurrency <= 0 {
+		settings.HistoryChunkConcurrency = DefaultHistoryChunkConcurrency
+	}
+	if settings.CatalogCacheTTL == "" {
+		settings.CatalogCacheTTL = DefaultCatalogCacheTTL
+	}
+	settings.CatalogCacheTTLDuration, err = ParseDuration(settings.CatalogCacheTTL)
+	if err != nil || settings.CatalogCacheTTLDuration <= 0 {
+		return nil, fmt.Errorf("invalid catalog cache TTL %q", settings.CatalogCacheTTL)
+	}
+
+	if settings.Timezone == "" {
+		settings.Timezone = "UTC"
+	}
+	settings.Location, err = time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", settings.Timezone, err)
 	}
 
 	settings.Secrets = loadSecretPluginSettings(source.DecryptedSecureJSONData)
@@ -29,6 +174,31 @@ func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*PluginSetti
 	return &settings, nil
 }
 
+// normalizeBaseUrl validates raw as an absolute http(s) URL and strips any
+// trailing slash. An empty value resolves to DefaultBaseUrl.
+func normalizeBaseUrl(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return DefaultBaseUrl, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid base URL %q: scheme must be http or https", raw)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q: missing host", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid base URL %q: query and fragment are not allowed", raw)
+	}
+
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
 func loadSecretPluginSettings(source map[string]string) *SecretPluginSettings {
 	return &SecretPluginSettings{
 		ApiKey: source["apiKey"],
@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/init/in-view/pkg/models"
+)
+
+// Aggregation functions selectable per query.
+const (
+	aggAvg             = "avg"
+	aggTimeWeightedAvg = "twavg"
+	aggMin             = "min"
+	aggMax             = "max"
+	aggSum             = "sum"
+	aggCount           = "count"
+	aggFirst           = "first"
+	aggLast            = "last"
+	aggRange           = "range"
+	aggStdDev          = "stddev"
+)
+
+func validAggregation(fn string) bool {
+	switch fn {
+	case "", aggAvg, aggTimeWeightedAvg, aggMin, aggMax, aggSum, aggCount, aggFirst, aggLast, aggRange, aggStdDev:
+		return true
+	default:
+		return false
+	}
+}
+
+// bucketSize returns the aggregation and delta bucket width: the query's
+// explicit bucket size, or Grafana's interval when none is set.
+func (qm queryModel) bucketSize(query backend.DataQuery) (time.Duration, error) {
+	if qm.BucketSize == "" {
+		if query.Interval <= 0 {
+			return 0, errors.New("aggregation and delta need a bucket size")
+		}
+		return query.Interval, nil
+	}
+
+	size, err := models.ParseDuration(qm.BucketSize)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid bucket size %q", qm.BucketSize)
+	}
+	return size, nil
+}
+
+// bucketStart returns the start of the bucket of the given size holding t,
+// with buckets aligned to the wall clock in loc rather than to UTC. Buckets of
+// whole days start at local midnight and shorter ones at wall-clock multiples
+// of size, so a bucket spanning a DST change is shorter or longer than size.
+func bucketStart(t time.Time, size time.Duration, loc *time.Location) time.Time {
+	const day = 24 * time.Hour
+
+	local := t.In(loc)
+	if size%day == 0 {
+		// Count days from the zero time, as Truncate does, which keeps
+		// weekly buckets starting on Mondays.
+		y, m, d := local.Date()
+		n := (time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() - time.Time{}.Unix()) / int64(day/time.Second)
+		return time.Date(y, m, d-int(n%int64(size/day)), 0, 0, 0, 0, loc)
+	}
+
+	_, offset := local.Zone()
+	start := alignWallClock(t, size, offset)
+	// When the offset changed between the aligned wall time and t, the
+	// wall clock there ran with the earlier offset.
+	if _, before := start.In(loc).Zone(); before != offset {
+		start = alignWallClock(start, size, before)
+	}
+	return start
+}
+
+// alignWallClock truncates t to a multiple of size on a wall clock offset
+// seconds east of UTC.
+func alignWallClock(t time.Time, size time.Duration, offset int) time.Time {
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(size).Add(-shift)
+}
+
+// bucketEnd returns the end of the bucket starting at start, which is the
+// start of the next one.
+func bucketEnd(start time.Time, size time.Duration, loc *time.Location) time.Time {
+	// A bucket stretched by a DST change holds start.Add(size) as well.
+	for next := start.Add(size); ; next = next.Add(size) {
+		if end := bucketStart(next, size, loc); end.After(start) {
+			return end
+		}
+	}
+}
+
+// aggregate replaces the points of s with one point per time bucket of the
+// given size, aligned in loc and stamped with the bucket start. Empty buckets
+// are skipped, null samples are ignored and a bucket holding only nulls stays
+// null. Each bucket keeps the worst quality of its samples.
+func (s *series) aggregate(fn string, size time.Duration, loc *time.Location) {
+	if fn == "" || len(s.points) == 0 {
+		return
+	}
+
+	var out []LiveValueTimeseries
+	for start := 0; start < len(s.points); {
+		bucket := bucketStart(s.points[start].Timestamp, size, loc)
+		next := bucketEnd(bucket, size, loc)
+		end := start
+		for end < len(s.points) && s.points[end].Timestamp.Before(next) {
+			end++
+		}
+
+		p := LiveValueTimeseries{Timestamp: bucket, Quality: s.points[start].Quality}
+		valid := make([]LiveValueTimeseries, 0, end-start)
+		for _, sample := range s.points[start:end] {
+			p.Quality = min(p.Quality, sample.Quality)
+			if !sample.Null {
+				valid = append(valid, sample)
+			}
+		}
+
+		switch {
+		case len(valid) == 0:
+			p.Null = fn != aggCount
+		case fn == aggTimeWeightedAvg:
+			p.Value = timeWeightedAvg(s.points, start, end, bucket, next)
+		default:
+			p.Value = reduce(fn, valid)
+		}
+		out = append(out, p)
+		start = end
+	}
+	s.points = out
+}
+
+// reduce applies the aggregation fn to the non-empty bucket.
+func reduce(fn string, bucket []LiveValueTimeseries) float64 {
+	switch fn {
+	case aggCount:
+		return float64(len(bucket))
+	case aggFirst:
+		return bucket[0].Value
+	case aggLast:
+		return bucket[len(bucket)-1].Value
+	}
+
+	lo, hi, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, p := range bucket {
+		lo = math.Min(lo, p.Value)
+		hi = math.Max(hi, p.Value)
+		sum += p.Value
+	}
+	mean := sum / float64(len(bucket))
+
+	switch fn {
+	case aggMin:
+		return lo
+	case aggMax:
+		return hi
+	case aggSum:
+		return sum
+	case aggRange:
+		return hi - lo
+	case aggStdDev:
+		var sq float64
+		for _, p := range bucket {
+			sq += (p.Value - mean) * (p.Value - mean)
+		}
+		return math.Sqrt(sq / float64(len(bucket)))
+	default:
+		return mean
+	}
+}
+
+// timeWeightedAvg averages points[start:end] over [from, to), weighting each
+// sample by how long it was the current value. The sample before the bucket,
+// if any, is carried in from the bucket start. Null samples count as unknown
+// time and are left out of the average.
+func timeWeightedAvg(points []LiveValueTimeseries, start, end int, from, to time.Time) float64 {
+	i := start
+	if start > 0 {
+		i = start - 1
+	}
+
+	var weighted, total float64
+	for ; i < end; i++ {
+		if points[i].Null {
+			continue
+		}
+		segStart := points[i].Timestamp
+		if segStart.Before(from) {
+			segStart = from
+		}
+		segEnd := to
+		if i+1 < len(points) && points[i+1].Timestamp.Before(to) {
+			segEnd = points[i+1].Timestamp
+		}
+		if d := segEnd.Sub(segStart).Seconds(); d > 0 {
+			weighted += points[i].Value * d
+			total += d
+		}
+	}
+
+	if total == 0 {
+		var valid []LiveValueTimeseries
+		for _, p := range points[start:end] {
+			if !p.Null {
+				valid = append(valid, p)
+			}
+		}
+		return reduce(aggAvg, valid)
+	}
+	return weighted / total
+}
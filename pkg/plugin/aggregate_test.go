@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAggregate(t *testing.T) {
+	// 0..9 over ten seconds, aggregated into 5s buckets.
+	values := func(i int) float64 { return float64(i) }
+
+	tests := []struct {
+		fn   string
+		want []float64
+	}{
+		{aggAvg, []float64{2, 7}},
+		{aggMin, []float64{0, 5}},
+		{aggMax, []float64{4, 9}},
+		{aggSum, []float64{10, 35}},
+		{aggCount, []float64{5, 5}},
+		{aggFirst, []float64{0, 5}},
+		{aggLast, []float64{4, 9}},
+		{aggRange, []float64{4, 4}},
+		{aggTimeWeightedAvg, []float64{2, 7}},
+	}
+	for _, tt := range tests {
+		s := &series{points: testPoints(10, values)}
+		s.aggregate(tt.fn, 5*time.Second, time.UTC)
+
+		if len(s.points) != len(tt.want) {
+			t.Fatalf("%s: got %d buckets, want %d", tt.fn, len(s.points), len(tt.want))
+		}
+		for i, p := range s.points {
+			if math.Abs(p.Value-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: bucket %d = %v, want %v", tt.fn, i, p.Value, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestTimeWeightedAvg(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 0 for the first 45 minutes, then 100 for the last 15.
+	s := &series{points: []LiveValueTimeseries{
+		{Timestamp: t0, Value: 0},
+		{Timestamp: t0.Add(45 * time.Minute), Value: 100},
+	}}
+	s.aggregate(aggTimeWeightedAvg, time.Hour, time.UTC)
+
+	if len(s.points) != 1 || s.points[0].Value != 25 {
+		t.Fatalf("got %+v, want a single bucket of 25", s.points)
+	}
+}
+
+func TestAggregateLocalBuckets(t *testing.T) {
+	loc := time.FixedZone("UTC+05:30", 5*3600+1800)
+	// Two samples either side of local midnight, which is 18:30 UTC.
+	s := &series{points: []LiveValueTimeseries{
+		{Timestamp: time.Date(2024, 1, 1, 23, 0, 0, 0, loc), Value: 1},
+		{Timestamp: time.Date(2024, 1, 2, 1, 0, 0, 0, loc), Value: 2},
+	}}
+	s.aggregate(aggSum, 24*time.Hour, loc)
+
+	want := []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, loc), time.Date(2024, 1, 2, 0, 0, 0, 0, loc)}
+	if len(s.points) != len(want) {
+		t.Fatalf("got %+v, want daily buckets at local midnight", s.points)
+	}
+	for i, p := range s.points {
+		if !p.Timestamp.Equal(want[i]) {
+			t.Errorf("bucket %d starts at %s, want %s", i, p.Timestamp.In(loc), want[i])
+		}
+	}
+}
+
+func TestAggregateAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Vienna")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Hourly samples from noon before to noon after the 23-hour day on
+	// which Vienna switches to summer time.
+	var points []LiveValueTimeseries
+	for at := time.Date(2024, 3, 30, 12, 0, 0, 0, loc); !at.After(time.Date(2024, 4, 1, 12, 0, 0, 0, loc)); at = at.Add(time.Hour) {
+		points = append(points, LiveValueTimeseries{Timestamp: at, Value: 1})
+	}
+
+	s := &series{points: points}
+	s.aggregate(aggCount, 24*time.Hour, loc)
+
+	want := []struct {
+		start time.Time
+		count float64
+	}{
+		{time.Date(2024, 3, 30, 0, 0, 0, 0, loc), 12},
+		{time.Date(2024, 3, 31, 0, 0, 0, 0, loc), 23},
+		{time.Date(2024, 4, 1, 0, 0, 0, 0, loc), 13},
+	}
+	if len(s.points) != len(want) {
+		t.Fatalf("got %+v, want one bucket per local day", s.points)
+	}
+	for i, p := range s.points {
+		if !p.Timestamp.Equal(want[i].start) || p.Value != want[i].count {
+			t.Errorf("bucket %d: %s holds %v, want %s holding %v", i, p.Timestamp.In(loc), p.Value, want[i].start, want[i].count)
+		}
+	}
+
+	// Two-hour buckets stretch over the skipped hour.
+	start := bucketStart(time.Date(2024, 3, 31, 3, 30, 0, 0, loc), 2*time.Hour, loc)
+	if want := time.Date(2024, 3, 31, 0, 0, 0, 0, loc); !start.Equal(want) {
+		t.Errorf("2h bucket starts at %s, want %s", start.In(loc), want)
+	}
+	if end, want := bucketEnd(start, 2*time.Hour, loc), time.Date(2024, 3, 31, 4, 0, 0, 0, loc); !end.Equal(want) {
+		t.Errorf("2h bucket ends at %s, want %s", end.In(loc), want)
+	}
+}
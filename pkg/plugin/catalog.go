@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+)
+
+// catalogRetryAfter is how long a failed catalog refresh is remembered
+// before the next attempt, unless the TTL is shorter.
+const catalogRetryAfter = time.Minute
+
+// variableCatalog caches the InView variable catalog, which carries the
+// units, decimals and ranges of the variables.
+type variableCatalog struct {
+	client *inview.Client
+	ttl    time.Duration
+	// ctx bounds the refreshes, which are not tied to any one caller.
+	ctx context.Context
+
+	mu        sync.Mutex
+	variables map[int]inview.Variables
+	fetched   time.Time
+	failed    time.Time
+	// refreshing is closed once the refresh in flight, if any, is done.
+	refreshing chan struct{}
+}
+
+func newVariableCatalog(ctx context.Context, client *inview.Client, ttl time.Duration) *variableCatalog {
+	return &variableCatalog{client: client, ttl: ttl, ctx: ctx}
+}
+
+// lookup returns the catalog entries by variable ID, refreshing the cache
+// once it is older than the TTL. Concurrent callers share a single refresh,
+// which runs on the catalog's context rather than any one of theirs, and stop
+// waiting for it when their own context ends. A failed refresh keeps serving the previous catalog, if
+// any, since the metadata only affects formatting, and is not retried for
+// catalogRetryAfter.
+func (c *variableCatalog) lookup(ctx context.Context) map[int]inview.Variables {
+	c.mu.Lock()
+	if c.current() {
+		defer c.mu.Unlock()
+		return c.variables
+	}
+	done := c.refreshing
+	if done == nil {
+		done = make(chan struct{})
+		c.refreshing = done
+		go c.refresh(c.ctx, done)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.variables
+}
+
+// current reports whether the cache needs no refresh: the catalog is within
+// its TTL, or the last refresh failed too recently to try again. c.mu must
+// be held.
+func (c *variableCatalog) current() bool {
+	if c.variables != nil && time.Since(c.fetched) < c.ttl {
+		return true
+	}
+	return time.Since(c.failed) < min(c.ttl, catalogRetryAfter)
+}
+
+// refresh fetches the catalog into the cache and closes done.
+func (c *variableCatalog) refresh(ctx context.Context, done chan struct{}) {
+	list, err := c.client.ListVariables(ctx, inview.VariablesOptions{
+		SkipFilterConns: true,
+		SkipPagination:  true,
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer close(done)
+	c.refreshing = nil
+
+	if err != nil {
+		log.DefaultLogger.Warn("PLUGIN QUERY -- Variable catalog refresh failed", "error", err)
+		c.failed = time.Now()
+		return
+	}
+
+	variables := make(map[int]inview.Variables, len(list))
+	for _, v := range list {
+		variables[v.ID] = v
+	}
+	c.variables, c.fetched = variables, time.Now()
+}
+
+// fieldConfig returns the value field config of a series from its catalog
+// entry. Values no longer in the variable's unit, such as counts or rates,
+// only keep the display name; values no longer within the variable's range,
+// such as sums or deltas, keep the unit but not the range and decimals.
+func fieldConfig(name string, v inview.Variables, unitChanged, rangeChanged bool) *data.FieldConfig {
+	config := &data.FieldConfig{
+		DisplayNameFromDS: name,
+		Description:       v.Description,
+	}
+	if unitChanged {
+		return config
+	}
+
+	config.Unit = v.Unit
+	if rangeChanged {
+		return config
+	}
+	if v.Decimals != nil {
+		decimals := uint16(max(*v.Decimals, 0))
+		config.Decimals = &decimals
+	}
+	if v.Min != nil {
+		minValue := data.ConfFloat64(*v.Min)
+		config.Min = &minValue
+	}
+	if v.Max != nil {
+		maxValue := data.ConfFloat64(*v.Max)
+		config.Max = &maxValue
+	}
+	return config
+}
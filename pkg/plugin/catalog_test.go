@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVariableCatalogCache(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`[{"id":7,"variableName":"Pressure","unit":"pressurebar","decimals":2,"minValue":0,"maxValue":250}]`))
+	}))
+	defer srv.Close()
+
+	ds := newTestDatasource(t, srv.URL)
+
+	for range 3 {
+		ds.catalog.lookup(context.Background())
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("catalog fetched %d times, want 1", n)
+	}
+
+	v := ds.catalog.lookup(context.Background())[7]
+	config := fieldConfig("Pressure", v, false, false)
+	if config.Unit != "pressurebar" || *config.Decimals != 2 || *config.Max != 250 {
+		t.Errorf("unexpected field config %+v", config)
+	}
+
+	// A sum of pressures is still in bar, but not within 0-250.
+	sum := queryModel{Aggregation: aggSum}
+	config = fieldConfig("Pressure", v, sum.changesUnit(), sum.changesRange())
+	if config.Unit != "pressurebar" || config.Decimals != nil || config.Min != nil || config.Max != nil {
+		t.Errorf("unexpected field config for a sum %+v", config)
+	}
+}
+
+func TestVariableCatalogFailure(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-release
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":7,"variableName":"Pressure"}]`))
+	}))
+	defer srv.Close()
+
+	catalog := newTestDatasource(t, srv.URL).catalog
+
+	// A caller that gives up does not wait for the refresh.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if v := catalog.lookup(ctx); v != nil {
+		t.Fatalf("got %v before the first refresh finished", v)
+	}
+
+	// Other callers wait for the same refresh, which the canceled caller
+	// did not abort, and do not retry the failure right away.
+	close(release)
+	for range 3 {
+		if v := catalog.lookup(context.Background()); v != nil {
+			t.Fatalf("got %v from a failed refresh", v)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("catalog fetched %d times, want 1", n)
+	}
+
+	catalog.mu.Lock()
+	catalog.failed = time.Now().Add(-catalogRetryAfter)
+	catalog.mu.Unlock()
+	if v := catalog.lookup(context.Background()); v[7].VariableName != "Pressure" {
+		t.Errorf("got %v after the retry delay, want the catalog", v)
+	}
+}
+
+func TestDisposeStopsCatalogRefresh(t *testing.T) {
+	canceled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer srv.Close()
+
+	ds := newTestDatasource(t, srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ds.catalog.lookup(ctx)
+
+	ds.Dispose()
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("catalog refresh was not canceled by Dispose")
+	}
+}
@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+)
+
+// forEachLimit calls fn for every index in [0, n) with at most limit calls in
+// flight. After the first error the context passed to the remaining calls is
+// canceled, no further calls are started and that error is returned.
+func forEachLimit(ctx context.Context, n, limit int, fn func(ctx context.Context, i int) error) error {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		slots    = make(chan struct{}, limit)
+	)
+
+	for i := 0; i < n; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
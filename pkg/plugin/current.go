@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+	"github.com/init/in-view/pkg/models"
+)
+
+// defaultCurrentLookback is how far back the current-value mode looks for
+// the latest sample when the query does not say.
+const defaultCurrentLookback = 24 * time.Hour
+
+// currentLookback parses CurrentLookback.
+func (qm queryModel) currentLookback() (time.Duration, error) {
+	if qm.CurrentLookback == "" {
+		return defaultCurrentLookback, nil
+	}
+	d, err := models.ParseDuration(qm.CurrentLookback)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid current lookback %q", qm.CurrentLookback)
+	}
+	return d, nil
+}
+
+// currentSample is the latest sample of a variable.
+type currentSample struct {
+	value   any
+	time    time.Time
+	quality int
+}
+
+// fetchCurrent returns the latest sample of every variable in ids, looking
+// back from the end of the query range, or from now when the range ends in
+// the future. Samples with unreadable timestamps are skipped and reported in
+// the returned notices.
+func (d *Datasource) fetchCurrent(ctx context.Context, qm queryModel, query backend.DataQuery, ids []int) (map[int]currentSample, []data.Notice, error) {
+	lookback, _ := qm.currentLookback()
+	to := query.TimeRange.To
+	if now := time.Now(); to.IsZero() || to.After(now) {
+		to = now
+	}
+
+	times := timestampParser{loc: d.settings.Location}
+	raw, err := d.client.GetLiveValues(ctx, inview.HistoryOptions{From: to.Add(-lookback), To: to, VariableIDs: ids})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	latest := make(map[int]currentSample, len(ids))
+	for _, r := range raw {
+		t := times.parse(r.Timestamp)
+		if t == nil {
+			continue
+		}
+		if cur, ok := latest[r.VariableId]; !ok || !t.Before(cur.time) {
+			latest[r.VariableId] = currentSample{value: r.Value, time: *t, quality: r.Quality}
+		}
+	}
+	return latest, times.notices(), nil
+}
+
+// currentFrame builds one row per selected variable with its latest value,
+// timestamp, quality and age at now. The value field is numeric, boolean or
+// string depending on the values; mixed values are shown as text. Variables
+// without a sample in the lookback window have null columns.
+func currentFrame(qm queryModel, latest map[int]currentSample, now time.Time) *data.Frame {
+	n := len(qm.Variables)
+	var (
+		names   = make([]string, n)
+		ids     = make([]int64, n)
+		times   = make([]*time.Time, n)
+		ages    = make([]*float64, n)
+		quality = make([]*string, n)
+		values  = make([]any, n)
+	)
+	for i, v := range qm.Variables {
+		names[i], ids[i] = v.VariableName, int64(v.ID)
+		s, ok := latest[v.ID]
+		if !ok {
+			continue
+		}
+		t, age, q := s.time, now.Sub(s.time).Seconds(), qualityText(s.quality)
+		times[i], ages[i], quality[i], values[i] = &t, &age, &q, s.value
+	}
+
+	return data.NewFrame("Current",
+		data.NewField("Variable", nil, names),
+		data.NewField("Variable ID", nil, ids),
+		currentValueField(values),
+		data.NewField("Time", nil, times),
+		data.NewField("Age", nil, ages).SetConfig(&data.FieldConfig{Unit: "s"}),
+		data.NewField("Quality", nil, quality),
+	)
+}
+
+// currentValueField returns the values as a nullable number, boolean or
+// string field, using the narrowest type that fits all of them.
+func currentValueField(values []any) *data.Field {
+	numeric, boolean := true, true
+	for _, v := range values {
+		switch v.(type) {
+		case nil:
+		case float64:
+			boolean = false
+		case bool:
+			numeric = false
+		default:
+			numeric, boolean = false, false
+		}
+	}
+
+	switch {
+	case numeric:
+		out := make([]*float64, len(values))
+		for i, v := range values {
+			if f, ok := v.(float64); ok {
+				out[i] = &f
+			}
+		}
+		return data.NewField("Value", nil, out)
+	case boolean:
+		out := make([]*bool, len(values))
+		for i, v := range values {
+			if b, ok := v.(bool); ok {
+				out[i] = &b
+			}
+		}
+		return data.NewField("Value", nil, out)
+	default:
+		out := make([]*string, len(values))
+		for i, v := range values {
+			if v != nil {
+				s := fmt.Sprint(v)
+				out[i] = &s
+			}
+		}
+		return data.NewField("Value", nil, out)
+	}
+}
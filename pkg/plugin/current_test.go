@@ -0,0 +1,30 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+)
+
+func TestCurrentFrame(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	qm := queryModel{Variables: []inview.Variables{{ID: 1, VariableName: "Pump"}, {ID: 2, VariableName: "Offline"}}}
+	latest := map[int]currentSample{1: {value: true, time: now.Add(-time.Minute), quality: qualityCodeGood}}
+
+	frame := currentFrame(qm, latest, now)
+	if got := frame.Fields[2].Type(); got != data.FieldTypeNullableBool {
+		t.Errorf("value field type = %v, want nullable bool", got)
+	}
+	if age, _ := frame.Fields[4].ConcreteAt(0); age != 60.0 {
+		t.Errorf("age = %v, want 60", age)
+	}
+	if v, ok := frame.Fields[2].ConcreteAt(1); ok {
+		t.Errorf("variable without a sample = %v, want null", v)
+	}
+
+	if got := currentValueField([]any{1.5, "open"}).Type(); got != data.FieldTypeNullableString {
+		t.Errorf("mixed value field type = %v, want nullable string", got)
+	}
+}
@@ -4,45 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	 "io"        
-	"io/ioutil"
 	"net/http"
-	"net/url"
-	"sort"
+	"sync"
 	"time"
-	"strings"
+
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
 	"github.com/init/in-view/pkg/models"
-    "strconv"
 )
 
-var GlobalBaseUrl string = "https://cloud.oilfield-monitor.com"
-
-
-// UnmarshalJSON implements the json.Unmarshaler interface
-func (ct *CustomTime) UnmarshalJSON(data []byte) error {
-	var s string
-	if err := json.Unmarshal(data, &s); err != nil {
-		return err
-	}
-
-	// Parse the time without requiring timezone information
-	t, err := time.Parse("2006-01-02T15:04:05", s)
-	if err != nil {
-		// Try parsing with timezone if the first attempt fails
-		t, err = time.Parse(time.RFC3339, s)
-		if err != nil {
-			return err
-		}
-	}
-
-	ct.Time = t
-	return nil
-}
-
 // Make sure Datasource implements required interfaces. This is important to do
 // since otherwise we will only get a not implemented error response from plugin in
 // runtime. In this example datasource instance implements backend.QueryDataHandler,
@@ -55,20 +29,82 @@ var (
 	_ backend.CallResourceHandler   = (*Datasource)(nil)
 )
 
-// NewDatasource creates a new datasource instance.
-func NewDatasource(_ context.Context, _ backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
-	return &Datasource{}, nil
+// NewDatasource creates a new datasource instance. Settings are parsed once
+// here; the instance owns everything derived from them until Dispose.
+func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+	config, err := models.LoadPluginSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := settings.HTTPClientOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("http client options: %w", err)
+	}
+	timeouts := config.TimeoutOptions()
+	opts.Timeouts = &timeouts
+
+	// The SDK wraps the transport in middlewares that hide
+	// CloseIdleConnections, so keep a handle on it for Dispose.
+	var transport *http.Transport
+	configure := opts.ConfigureTransport
+	opts.ConfigureTransport = func(o httpclient.Options, t *http.Transport) {
+		if configure != nil {
+			configure(o, t)
+		}
+		transport = t
+	}
+
+	httpClient, err := httpclient.New(opts)
+	if err != nil {
+		return nil, fmt.Errorf("http client: %w", err)
+	}
+
+	client := inview.New(config.BaseUrl, config.Secrets.ApiKey, httpClient,
+		inview.WithRetryPolicy(config.RetryPolicy()),
+		inview.WithLocation(config.Location),
+	)
+
+	// Background work of the instance runs until Dispose, not for the
+	// lifetime of the request that happened to start it.
+	workCtx, cancel := context.WithCancel(context.Background())
+
+	return &Datasource{
+		settings:   config,
+		transport:  transport,
+		client:     client,
+		catalog:    newVariableCatalog(workCtx, client, config.CatalogCacheTTLDuration),
+		querySlots: make(chan struct{}, config.MaxConcurrentQueries),
+		cancel:     cancel,
+	}, nil
 }
 
-// Datasource is an example datasource which can respond to data queries, reports
-// its health and has streaming skills.
-type Datasource struct{}
+// Datasource is an InView datasource instance. Grafana creates one per
+// configured datasource through datasource.Manage.
+type Datasource struct {
+	settings  *models.PluginSettings
+	transport *http.Transport
+	client    *inview.Client
+	catalog   *variableCatalog
+
+	// querySlots bounds how many queries run at once across all requests
+	// served by this instance.
+	querySlots chan struct{}
+
+	// cancel stops the instance's background work.
+	cancel context.CancelFunc
+}
 
 // Dispose here tells plugin SDK that plugin wants to clean up resources when a new instance
 // created. As soon as datasource settings change detected by SDK old datasource instance will
-// be disposed and a new one will be created using NewSampleDatasource factory function.
+// be disposed and a new one will be created using NewDatasource factory function.
 func (d *Datasource) Dispose() {
-	// Clean up datasource instance resources.
+	if d.cancel != nil {
+		d.cancel()
+	}
+	if d.transport != nil {
+		d.transport.CloseIdleConnections()
+	}
 }
 
 // QueryData handles multiple queries and returns multiple responses.
@@ -79,380 +115,207 @@ func (d *Datasource) QueryData(ctx context.Context, req *backend.QueryDataReques
 	// create response struct
 	response := backend.NewQueryDataResponse()
 
-	// loop over queries and execute them individually.
-	for _, q := range req.Queries {
-		res := d.query(ctx, req.PluginContext, q, req)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
 
-		// save the response in a hashmap
-		// based on with RefID as identifier
-		response.Responses[q.RefID] = res
+	// run the queries concurrently, bounded by the instance's query slots.
+	for _, q := range req.Queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := d.runQuery(ctx, q)
+
+			// save the response in a hashmap
+			// based on with RefID as identifier
+			mu.Lock()
+			response.Responses[q.RefID] = res
+			mu.Unlock()
+		}()
 	}
+	wg.Wait()
 
 	return response, nil
 }
 
+// runQuery waits for a free query slot and executes q. A panic is reported as
+// an error for this query only.
+func (d *Datasource) runQuery(ctx context.Context, q backend.DataQuery) (res backend.DataResponse) {
+	select {
+	case d.querySlots <- struct{}{}:
+		defer func() { <-d.querySlots }()
+	case <-ctx.Done():
+		return errorResponse(ctx.Err())
+	}
 
-func (d *Datasource) query(_ context.Context, pCtx backend.PluginContext, query backend.DataQuery, req *backend.QueryDataRequest) backend.DataResponse {
+	defer func() {
+		if r := recover(); r != nil {
+			log.DefaultLogger.Error("PLUGIN QUERY -- Query panicked", "refId", q.RefID, "panic", r)
+			res = backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("query failed: %v", r))
+		}
+	}()
+
+	return d.query(ctx, q)
+}
+
+func (d *Datasource) query(ctx context.Context, query backend.DataQuery) backend.DataResponse {
 	var response backend.DataResponse
 	var qm queryModel
 
-
 	response.Frames = []*data.Frame{}
 
-	log.DefaultLogger.Info("PLUGIN QUERY -- START ----------------------------")
-	log.DefaultLogger.Info("PLUGIN QUERY -- Raw query JSON", "json", string(query.JSON))
+	log.DefaultLogger.Debug("PLUGIN QUERY -- Raw query JSON", "json", string(query.JSON))
 
-	// Unmarshal query JSON first
 	if err := json.Unmarshal(query.JSON, &qm); err != nil {
 		log.DefaultLogger.Error("PLUGIN QUERY -- JSON unmarshal failed", "error", err)
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("json unmarshal: %v", err))
+		return backend.ErrDataResponseWithSource(backend.StatusBadRequest, backend.ErrorSourceDownstream, fmt.Sprintf("json unmarshal: %v", err))
 	}
 
-	log.DefaultLogger.Info("PLUGIN QUERY -- Parsed QueryModel", "queryText", qm.QueryText, "IsAlarm", qm.IsAlarm, "IsEvent", qm.IsEvent, "IsLive", qm.IsLive)
-
-	config, _ := models.LoadPluginSettings(*req.PluginContext.DataSourceInstanceSettings)
-	log.DefaultLogger.Info("PLUGIN QUERY -- Loaded Plugin Settings", "baseUrl", GlobalBaseUrl)
-
-	from := query.TimeRange.From.UTC().Format("2006-01-02T15:04:05")
-	to := query.TimeRange.To.UTC().Format("2006-01-02T15:04:05")
-
-	// qm.IsLive = false;
-	// qm.IsAlarm = false;
-	// qm.IsEvent = true;
-
-	// qm.VariableIds = []int{1, 2, 3}
-
-	// stringSlice := make([]string, len(qm.VariableIds))
-    // for i, n := range qm.VariableIds {
-    //     stringSlice[i] = strconv.Itoa(n)
-    // }
-
-    // varIdsString := strings.Join(stringSlice, ",")
-
+	// Invalid query options are the user's to fix.
+	if err := qm.validate(query); err != nil {
+		return backend.ErrDataResponseWithSource(backend.StatusBadRequest, backend.ErrorSourceDownstream, err.Error())
+	}
 
+	log.DefaultLogger.Debug("PLUGIN QUERY -- Parsed QueryModel", "IsAlarm", qm.IsAlarm, "IsEvent", qm.IsEvent, "IsLive", qm.IsLive, "IsStatistics", qm.IsStatistics, "IsCurrent", qm.IsCurrent, "IsAlarmKPI", qm.IsAlarmKPI)
 
-	varIds := make([]string, len(qm.Variables))
+	retries := &inview.RetryStats{}
+	ctx = inview.WithRetryStats(ctx, retries)
 
-	for i, v := range qm.Variables {
-		varIds[i] = strconv.Itoa(v.ID) 
-	}
+	varIds := qm.variableIDs()
+	maxRows := d.maxRows(qm)
 
-	joinedVarIds := strings.Join(varIds, ",")
-	pageIndex := qm.PageIndex
-	pageSize := qm.PageSize
-	if pageIndex <= 0 {
-		pageIndex = 0
-	}
-	if pageSize <= 0 {
-		pageSize = 10
-	}
-	var urlStr string
 	if qm.IsAlarm {
-		urlStr = fmt.Sprintf("%s/api/public/alarms?dateFrom=%s&dateTo=%s&varId=%s&locationPrefix=%s&pageIndex=%s&pageSize=%s",
-		GlobalBaseUrl,
-		url.QueryEscape(from),
-		url.QueryEscape(to),
-		url.QueryEscape(joinedVarIds),
-		url.QueryEscape(qm.Prefix),                      
-		url.QueryEscape(strconv.Itoa(pageIndex)),
-		url.QueryEscape(strconv.Itoa(pageSize)),
-)
-		client := &http.Client{}
-		req2, err := http.NewRequest("GET", urlStr, nil)
+		opts := inview.AlarmsOptions{
+			From:           query.TimeRange.From,
+			To:             query.TimeRange.To,
+			VariableIDs:    varIds,
+			LocationPrefix: qm.Prefix,
+		}
+		raw, truncated, err := fetchRows(ctx, qm, maxRows, func(ctx context.Context, pageIndex, pageSize int) ([]inview.AlarmLog, error) {
+			opts.PageIndex, opts.PageSize = pageIndex, pageSize
+			return d.client.GetAlarms(ctx, opts)
+		})
 		if err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- Failed to create HTTP request", "error", err)
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to create API request")
+			return errorResponse(err)
 		}
-
-		req2.Header.Set("Authorization", config.Secrets.ApiKey)
-		req2.Header.Set("Accept", "application/json")
-		log.DefaultLogger.Info("PLUGIN QUERY -- HTTP headers set")
-
-		resp, err := client.Do(req2)
+		frame, err := alarmsFrame(ctx, raw, d.settings.Location, time.Now(), qm.ActiveOnly)
 		if err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- HTTP request failed", "error", err)
-			return backend.ErrDataResponse(backend.StatusBadGateway, "API request failed")
-		}
-		defer resp.Body.Close()
-
-		log.DefaultLogger.Info("PLUGIN QUERY -- HTTP response received", "statusCode", resp.StatusCode)
-
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- Failed to read response body", "error", readErr)
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to read API response")
+			return errorResponse(err)
 		}
-
-		limit := 300
-		if len(body) < 300 {
-			limit = len(body)
-		}
-		log.DefaultLogger.Info("PLUGIN QUERY -- Raw API response (first 300 chars)", "body", string(body[:limit]))
-
-		if resp.StatusCode != http.StatusOK {
-			log.DefaultLogger.Error("PLUGIN QUERY -- API returned non-OK status", "status", resp.StatusCode)
-			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API Error (%d): %s", resp.StatusCode, string(body)))
+		if truncated {
+			frame.AppendNotices(truncatedNotice(maxRows))
 		}
-
-		var raw []AlarmLog
-		if err := json.Unmarshal(body, &raw); err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- JSON unmarshal response failed", "error", err)
-			log.DefaultLogger.Error("PLUGIN QUERY -- Raw response body", "body", string(body))
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to parse API response JSON")
-		}
-
-			frame := data.NewFrame(
-			"Alarms",
-			data.NewField("Description", nil, []string{}),
-			data.NewField("Activation Time", nil, []time.Time{}),
-			data.NewField("Termination Time", nil, []time.Time{}),
-		)
-
-		for _, alarm := range raw {
-			var activation, termination time.Time
-			var err error
-
-			if alarm.IwsAlarmActivationTime != "" {
-				activation, err = time.Parse("2006-01-02T15:04:05.999999", alarm.IwsAlarmActivationTime)
-				if err != nil {
-					log.DefaultLogger.Error("PLUGIN QUERY -- Time parse error", "field", "ActivationTime", "value", alarm.IwsAlarmActivationTime, "error", err)
-				}
-			}
-
-			if alarm.IwsAlarmTerminationTime != "" {
-				termination, err = time.Parse("2006-01-02T15:04:05.999999", alarm.IwsAlarmTerminationTime)
-				if err != nil {
-					log.DefaultLogger.Error("PLUGIN QUERY -- Time parse error", "field", "TerminationTime", "value", alarm.IwsAlarmTerminationTime, "error", err)
-				}
-			}
-
-			frame.AppendRow(alarm.IwsAlarmDescription, activation, termination)
-		}
-
 		response.Frames = append(response.Frames, frame)
 	}
 
 	if qm.IsEvent {
-		urlStr = fmt.Sprintf("%s/api/public/events?dateFrom=%s&dateTo=%s&varId=%s&locationPrefix=%s&opcTags=%s&pageIndex=%s&pageSize=%s",
-			GlobalBaseUrl, 
-			url.QueryEscape(from), 
-			url.QueryEscape(to), 
-			url.QueryEscape(joinedVarIds), 
-			url.QueryEscape(qm.Prefix),
-			url.QueryEscape(qm.OpcTags),
-			url.QueryEscape(strconv.Itoa(pageIndex)), 
-			url.QueryEscape(strconv.Itoa(pageSize)))
-		log.DefaultLogger.Info("PLUGIN QUERY -- EVENT URL", "url", urlStr)
-		
-		client := &http.Client{}
-		req2, err := http.NewRequest("GET", urlStr, nil)
+		opts := inview.EventsOptions{
+			From:           query.TimeRange.From,
+			To:             query.TimeRange.To,
+			VariableIDs:    varIds,
+			LocationPrefix: qm.Prefix,
+			OpcTags:        qm.OpcTags,
+		}
+		raw, truncated, err := fetchRows(ctx, qm, maxRows, func(ctx context.Context, pageIndex, pageSize int) ([]inview.EventLog, error) {
+			opts.PageIndex, opts.PageSize = pageIndex, pageSize
+			return d.client.GetEvents(ctx, opts)
+		})
 		if err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- Failed to create HTTP request", "error", err)
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to create API request")
+			return errorResponse(err)
 		}
-
-		req2.Header.Set("Authorization", config.Secrets.ApiKey)
-		req2.Header.Set("Accept", "application/json")
-		log.DefaultLogger.Info("PLUGIN QUERY -- HTTP headers set")
-
-		resp, err := client.Do(req2)
+		frame, err := eventsFrame(ctx, raw, d.settings.Location)
 		if err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- HTTP request failed", "error", err)
-			return backend.ErrDataResponse(backend.StatusBadGateway, "API request failed")
-		}
-		defer resp.Body.Close()
-
-		log.DefaultLogger.Info("PLUGIN QUERY -- HTTP response received", "statusCode", resp.StatusCode)
-
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- Failed to read response body", "error", readErr)
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to read API response")
-		}
-
-		limit := 300
-		if len(body) < 300 {
-			limit = len(body)
-		}
-		log.DefaultLogger.Info("PLUGIN QUERY -- Raw API response (first 300 chars)", "body", string(body[:limit]))
-
-		if resp.StatusCode != http.StatusOK {
-			log.DefaultLogger.Error("PLUGIN QUERY -- API returned non-OK status", "status", resp.StatusCode)
-			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API Error (%d): %s", resp.StatusCode, string(body)))
+			return errorResponse(err)
 		}
-
-		var raw []EventLog
-		if err := json.Unmarshal(body, &raw); err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- JSON unmarshal response failed", "error", err)
-			log.DefaultLogger.Error("PLUGIN QUERY -- Raw response body", "body", string(body))
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to parse API response JSON")
-		}
-
-			frame := data.NewFrame(
-			"Events",
-			data.NewField("Description", nil, []string{}),
-			data.NewField("Activation Time", nil, []time.Time{}),
-		)
-
-		for _, event := range raw {
-			var IwsEventTimestamp time.Time
-			var err error
-
-			if event.IwsEventTimestamp != "" {
-				IwsEventTimestamp, err = time.Parse("2006-01-02T15:04:05.999999", event.IwsEventTimestamp)
-				if err != nil {
-					log.DefaultLogger.Error("PLUGIN QUERY -- Time parse error", "field", "IwsEventTimestamp", "value", event.IwsEventTimestamp, "error", err)
-				}
-			}
-
-		
-
-			frame.AppendRow(event.IwsEventDescription, IwsEventTimestamp)
+		if truncated {
+			frame.AppendNotices(truncatedNotice(maxRows))
 		}
-
 		response.Frames = append(response.Frames, frame)
 	}
 
-	if qm.IsLive && len(varIds) != 0 && varIds != nil {
-			urlStr = fmt.Sprintf("%s/api/public/variables/getHistoryLoggedValuesV2?dateFrom=%s&dateTo=%s&varId=%s",
-			GlobalBaseUrl, url.QueryEscape(from), url.QueryEscape(to), url.QueryEscape(joinedVarIds))
-		log.DefaultLogger.Info("PLUGIN QUERY -- Final API URL", "url", urlStr)
-
-		client := &http.Client{}
-		req2, err := http.NewRequest("GET", urlStr, nil)
+	if historyIds := qm.historyIDs(); qm.IsLive && len(historyIds) != 0 {
+		raw, err := d.fetchHistory(ctx, query.TimeRange.From, query.TimeRange.To, historyIds)
 		if err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- Failed to create HTTP request", "error", err)
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to create API request")
+			return errorResponse(err)
 		}
-
-		req2.Header.Set("Authorization", config.Secrets.ApiKey)
-		req2.Header.Set("Accept", "application/json")
-		log.DefaultLogger.Info("PLUGIN QUERY -- HTTP headers set")
-
-		resp, err := client.Do(req2)
+		log.DefaultLogger.Debug("PLUGIN QUERY -- Parsed records", "count", len(raw))
+		frames, err := d.historyFrames(ctx, qm, query, raw)
 		if err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- HTTP request failed", "error", err)
-			return backend.ErrDataResponse(backend.StatusBadGateway, "API request failed")
-		}
-		defer resp.Body.Close()
-
-		log.DefaultLogger.Info("PLUGIN QUERY -- HTTP response received", "statusCode", resp.StatusCode)
-
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- Failed to read response body", "error", readErr)
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to read API response")
-		}
-
-		limit := 300
-		if len(body) < 300 {
-			limit = len(body)
-		}
-		log.DefaultLogger.Info("PLUGIN QUERY -- Raw API response (first 300 chars)", "body", string(body[:limit]))
-
-		if resp.StatusCode != http.StatusOK {
-			log.DefaultLogger.Error("PLUGIN QUERY -- API returned non-OK status", "status", resp.StatusCode)
-			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API Error (%d): %s", resp.StatusCode, string(body)))
+			return errorResponse(err)
 		}
+		response.Frames = append(response.Frames, frames...)
+	}
 
-		var raw []rawLiveValue
-		if err := json.Unmarshal(body, &raw); err != nil {
-			log.DefaultLogger.Error("PLUGIN QUERY -- JSON unmarshal response failed", "error", err)
-			log.DefaultLogger.Error("PLUGIN QUERY -- Raw response body", "body", string(body))
-			return backend.ErrDataResponse(backend.StatusInternal, "Failed to parse API response JSON")
+	if qm.IsAlarmKPI {
+		opts := inview.AlarmsOptions{
+			From:           query.TimeRange.From,
+			To:             query.TimeRange.To,
+			VariableIDs:    varIds,
+			LocationPrefix: qm.Prefix,
 		}
-
-		log.DefaultLogger.Info("PLUGIN QUERY -- Parsed records", "count", len(raw))
-
-		grouped := make(map[int][]LiveValueTimeseries)
-		for _, r := range raw {
-			t, err := time.Parse("2006-01-02T15:04:05", r.Timestamp)
-			if err != nil {
-				log.DefaultLogger.Error("PLUGIN QUERY -- Time parse error", "timestamp", r.Timestamp, "error", err)
-				continue
-			}
-			grouped[r.VariableId] = append(grouped[r.VariableId], LiveValueTimeseries{
-				Timestamp: t,
-				Value:     r.Value,
-			})
+		raw, truncated, err := fetchAllPages(ctx, allPagesPageSize, maxRows, func(ctx context.Context, pageIndex, pageSize int) ([]inview.AlarmLog, error) {
+			opts.PageIndex, opts.PageSize = pageIndex, pageSize
+			return d.client.GetAlarms(ctx, opts)
+		})
+		if err != nil {
+			return errorResponse(err)
 		}
-
-		for varId, values := range grouped {
-			log.DefaultLogger.Info("PLUGIN QUERY -- Grouped values", "VariableId", varId, "Count", len(values))
-			maxSamples := 3
-			for i, v := range values {
-				if i >= maxSamples {
-					log.DefaultLogger.Info("PLUGIN QUERY -- Skipping remaining values for VarId", "VariableId", varId)
-					break
-				}
-				log.DefaultLogger.Info("PLUGIN QUERY -- Sample value", "VariableId", varId, "Timestamp", v.Timestamp.Format("2006-01-02 15:04:05"), "Value", v.Value)
-			}
+		frames := alarmKPIFrames(qm, raw, query.TimeRange.From, query.TimeRange.To, d.settings.Location, time.Now())
+		if truncated {
+			frames[0].AppendNotices(truncatedNotice(maxRows))
 		}
+		response.Frames = append(response.Frames, frames...)
+	}
 
-		varNameMap := make(map[int]string, len(qm.Variables))
-		for _, v := range qm.Variables {
-			varNameMap[v.ID] = v.VariableName
+	if qm.IsStatistics && len(varIds) != 0 {
+		raw, err := d.fetchHistory(ctx, query.TimeRange.From, query.TimeRange.To, varIds)
+		if err != nil {
+			return errorResponse(err)
 		}
-
-		type item struct {
-			id       int
-			name     string 
-			sortName string 
+		frame, err := d.statisticsFrame(ctx, qm, raw)
+		if err != nil {
+			return errorResponse(err)
 		}
+		response.Frames = append(response.Frames, frame)
+	}
 
-		items := make([]item, 0, len(grouped))
-		for varId := range grouped {
-			name := varNameMap[varId]
-			if name == "" {
-				name = fmt.Sprintf("%d", varId)
-			}
-			items = append(items, item{id: varId, name: name, sortName: strings.ToLower(name)})
+	if qm.IsCurrent && len(varIds) != 0 {
+		latest, notices, err := d.fetchCurrent(ctx, qm, query, varIds)
+		if err != nil {
+			return errorResponse(err)
 		}
+		frame := currentFrame(qm, latest, time.Now())
+		frame.AppendNotices(notices...)
+		response.Frames = append(response.Frames, frame)
+	}
 
-		sort.Slice(items, func(i, j int) bool {
-			return items[i].sortName < items[j].sortName
+	if n := retries.Retries(); n > 0 {
+		appendNotice(response.Frames, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("InView requests were retried %d time(s)", n),
 		})
+	}
 
-		// Create frames using original names
-		for _, it := range items {
-			values := grouped[it.id]
-
-			frame := data.NewFrame(it.name)
-
-			times := make([]time.Time, len(values))
-			vals := make([]float64, len(values))
-
-			for i, v := range values {
-				times[i] = v.Timestamp
-				vals[i] = v.Value
-			}
-
-			frame.Fields = append(frame.Fields,
-				data.NewField("time", nil, times),
-				data.NewField("value", nil, vals),
-			)
+	return response
+}
 
-			response.Frames = append(response.Frames, frame)
-		}
+// maxRows returns the row cap for AllPages queries.
+func (d *Datasource) maxRows(qm queryModel) int {
+	if qm.MaxRows > 0 {
+		return qm.MaxRows
 	}
-	log.DefaultLogger.Info("PLUGIN QUERY -- END --------------------------------")
-	return response
+	return d.settings.MaxRows
 }
+
 // CheckHealth handles health checks sent from Grafana to the plugin.
 // The main use case for these health checks is the test button on the
 // datasource configuration page which allows users to verify that
 // a datasource is working as expected.
-func (d *Datasource) CheckHealth(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+func (d *Datasource) CheckHealth(_ context.Context, _ *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
 	res := &backend.CheckHealthResult{}
-	config, err := models.LoadPluginSettings(*req.PluginContext.DataSourceInstanceSettings)
-
-	if err != nil {
-		res.Status = backend.HealthStatusError
-		res.Message = "Unable to load settings"
-		return res, nil
-	}
 
-	if config.Secrets.ApiKey == "" {
+	if d.settings.Secrets.ApiKey == "" {
 		res.Status = backend.HealthStatusError
 		res.Message = "API key is missing"
 		return res, nil
@@ -463,168 +326,3 @@ func (d *Datasource) CheckHealth(_ context.Context, req *backend.CheckHealthRequ
 		Message: "Data source is working",
 	}, nil
 }
-
-func (ds *Datasource) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
-
-	config, _ := models.LoadPluginSettings(*req.PluginContext.DataSourceInstanceSettings)
-
-	if req.Path == "Variables" {
-
-		u, _ := url.Parse(req.URL)
-		values := u.Query()
-
-		if values.Get("page") == "" {
-			values.Set("page", "0")
-		}
-		if values.Get("itemsPerPage") == "" {
-			values.Set("itemsPerPage", "20")
-		}
-		if values.Get("skipFilterConns") == "" {
-			values.Set("skipFilterConns", "false")
-		}
-		if values.Get("connId") == "" {
-			values.Set("connId", "0")
-		}
-		if values.Get("likeParam") == "" {
-			values.Set("likeParam", "")
-		}
-
-		if values.Get("skipPagination") == "" {
-			values.Set("skipPagination", "true")
-		}
-
-		baseURL, _ := url.Parse(GlobalBaseUrl + "/api/public/variables-dto")
-
-		log.DefaultLogger.Info("PLUGIN QUERY -- BaseUrl", "url", GlobalBaseUrl)
-
-		baseURL.RawQuery = values.Encode()
-
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", baseURL.String(), nil)
-		if err != nil {
-			panic(err)
-		}
-
-		req.Header.Set("Authorization", config.Secrets.ApiKey)
-
-		resp, err := client.Do(req)
-		if err != nil {
-			panic(err)
-		}
-		defer resp.Body.Close()
-
-		body, _ := ioutil.ReadAll(resp.Body)
-
-		var data []Variables
-		err = json.Unmarshal(body, &data)
-		if err != nil {
-			fmt.Println("JSON unmarshal error:", err)
-			fmt.Println("Raw response:", string(body))
-			panic(err)
-		}
-
-		finalBody, err := json.Marshal(data)
-
-		if err != nil {
-
-			return sender.Send(&backend.CallResourceResponse{
-
-				Status: http.StatusInternalServerError,
-
-				Body: []byte(fmt.Sprintf("Error marshaling response: %v", err)),
-			})
-
-		}
-
-		return sender.Send(&backend.CallResourceResponse{
-
-			Status: http.StatusOK,
-
-			Headers: map[string][]string{"Content-Type": {"application/json"}},
-
-			Body: finalBody,
-		})
-
-	}
-
-	if req.Path == "Connections" {
-
-		u, _ := url.Parse(req.URL)
-		values := u.Query()
-
-		if values.Get("pageIndex") == "" {
-			values.Set("pageIndex", "0")
-		}
-		if values.Get("pageSize") == "" {
-			values.Set("pageSize", "10")
-		}
-		if values.Get("skipConnectionFilter") == "" {
-			values.Set("skipConnectionFilter", "false")
-		}
-
-		baseURL, _ := url.Parse(GlobalBaseUrl + "/api/public/connections")
-		baseURL.RawQuery = values.Encode()
-
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", baseURL.String(), nil)
-		if err != nil {
-			panic(err)
-		}
-		req.Header.Set("Authorization", config.Secrets.ApiKey)
-
-		resp, err := client.Do(req)
-		if err != nil {
-			panic(err)
-		}
-		defer resp.Body.Close()
-
-		body, _ := ioutil.ReadAll(resp.Body)
-
-		var data []Connections
-		err = json.Unmarshal(body, &data)
-		if err != nil {
-			fmt.Println("JSON unmarshal error:", err)
-			fmt.Println("Raw response:", string(body))
-			panic(err)
-		}
-		data = append(data, Connections{
-			ID:             0,
-			ConnectionName: "Internal",
-		})
-
-		sort.Slice(data, func(i, j int) bool {
-			return data[i].ID < data[j].ID
-		})
-
-		finalBody, err := json.Marshal(data)
-
-		if err != nil {
-
-			return sender.Send(&backend.CallResourceResponse{
-
-				Status: http.StatusInternalServerError,
-
-				Body: []byte(fmt.Sprintf("Error marshaling response: %v", err)),
-			})
-
-		}
-
-		return sender.Send(&backend.CallResourceResponse{
-
-			Status: http.StatusOK,
-
-			Headers: map[string][]string{"Content-Type": {"application/json"}},
-
-			Body: finalBody,
-		})
-
-	}
-
-	return sender.Send(&backend.CallResourceResponse{
-
-		Status: http.StatusNotFound,
-
-		Body: []byte(fmt.Sprintf("Unknown path: %s", req.Path)),
-	})
-
-}
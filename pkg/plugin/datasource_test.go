@@ -2,13 +2,23 @@ package plugin
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/init/in-view/pkg/inview"
 )
 
 func TestQueryData(t *testing.T) {
-	ds := Datasource{}
+	inst, err := NewDatasource(context.Background(), backend.DataSourceInstanceSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := inst.(*Datasource)
 
 	resp, err := ds.QueryData(
 		context.Background(),
@@ -26,3 +36,140 @@ func TestQueryData(t *testing.T) {
 		t.Fatal("QueryData must return a response")
 	}
 }
+
+// newTestDatasource returns a datasource for the InView API at baseURL.
+// settings are extra members of the JSON settings object, e.g.
+// `"historyChunk":"1d"`.
+func newTestDatasource(t *testing.T, baseURL string, settings ...string) *Datasource {
+	t.Helper()
+	jsonData := `{"baseUrl":"` + baseURL + `"`
+	for _, s := range settings {
+		jsonData += "," + s
+	}
+	inst, err := NewDatasource(context.Background(), backend.DataSourceInstanceSettings{
+		JSONData: []byte(jsonData + "}"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := inst.(*Datasource)
+	t.Cleanup(ds.Dispose)
+	return ds
+}
+
+func TestQueryDataConcurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := r.URL.Query().Get("locationPrefix")
+		if prefix == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		delay, _ := strconv.Atoi(prefix)
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	ds := newTestDatasource(t, srv.URL, `"maxConcurrentQueries":8`)
+
+	queries := []backend.DataQuery{
+		{RefID: "A", JSON: []byte(`{"isAlarm":true,"prefix":"50"}`)},
+		{RefID: "B", JSON: []byte(`{"isAlarm":true,"prefix":"100"}`)},
+		{RefID: "C", JSON: []byte(`{"isEvent":true,"prefix":"150"}`)},
+		{RefID: "D", JSON: []byte(`{"isEvent":true,"prefix":"200"}`)},
+		{RefID: "E", JSON: []byte(`{"isAlarm":true,"prefix":"fail"}`)},
+	}
+
+	start := time.Now()
+	resp, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{Queries: queries})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sequential execution would take at least 500ms.
+	if elapsed >= 400*time.Millisecond {
+		t.Fatalf("queries did not run concurrently, took %v", elapsed)
+	}
+
+	for _, refID := range []string{"A", "B", "C", "D"} {
+		if err := resp.Responses[refID].Error; err != nil {
+			t.Errorf("query %s: unexpected error %v", refID, err)
+		}
+	}
+	if resp.Responses["E"].Error == nil {
+		t.Error("query E: expected an error")
+	}
+}
+
+func TestDisposeClosesIdleConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	ds := newTestDatasource(t, srv.URL)
+	if _, err := ds.client.ListConnections(context.Background(), inview.ConnectionsOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ds.Dispose()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+}
+
+func TestQueryDataRejectsInvalidOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	defer srv.Close()
+
+	ds := newTestDatasource(t, srv.URL)
+	queries := []backend.DataQuery{
+		{RefID: "A", JSON: []byte(`{"isLive":true,"variableId":1,"aggregation":"avg","bucketSize":"soon"}`)},
+		{RefID: "B", JSON: []byte(`{"isLive":true,"variableId":1,"downsample":"median"}`)},
+		{RefID: "C", JSON: []byte(`{"isLive":true,"variableId":1,"expression":"$1 +"}`)},
+	}
+	resp, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{Queries: queries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, refID := range []string{"A", "B", "C"} {
+		r := resp.Responses[refID]
+		if r.Error == nil {
+			t.Errorf("query %s: expected an error", refID)
+			continue
+		}
+		if r.Status != backend.StatusBadRequest || r.ErrorSource != backend.ErrorSourceDownstream {
+			t.Errorf("query %s: status %v, source %q", refID, r.Status, r.ErrorSource)
+		}
+	}
+}
+
+func TestQueryDataIgnoresExpressionOutsideHistory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	ds := newTestDatasource(t, srv.URL)
+	// A leftover expression from a history query does not break alarms.
+	query := backend.DataQuery{RefID: "A", JSON: []byte(`{"isAlarm":true,"expression":"$unknown * 2"}`)}
+	resp, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{Queries: []backend.DataQuery{query}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Responses["A"].Error; err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
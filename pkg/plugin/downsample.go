@@ -0,0 +1,221 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Downsampling algorithms selectable per query.
+const (
+	downsampleLTTB      = "lttb"
+	downsampleMinMax    = "minmax"
+	downsampleFirstLast = "firstlast"
+	downsampleNone      = "none"
+)
+
+func validDownsample(algo string) bool {
+	switch algo {
+	case "", downsampleLTTB, downsampleMinMax, downsampleFirstLast, downsampleNone:
+		return true
+	default:
+		return false
+	}
+}
+
+// downsample caps s at maxPoints points using algo, LTTB by default, and
+// records the reduction ratio in a notice.
+func (s *series) downsample(algo string, maxPoints int) {
+	if algo == downsampleNone || maxPoints <= 0 || len(s.points) <= maxPoints {
+		return
+	}
+
+	before := len(s.points)
+	s.points, algo = downsamplePoints(algo, s.points, maxPoints)
+	s.notices = append(s.notices, downsampleNotice(before, len(s.points), "points", algo))
+}
+
+// downsamplePoints caps points at maxPoints using algo, LTTB by default, and
+// returns the name of the algorithm used.
+func downsamplePoints(algo string, points []LiveValueTimeseries, maxPoints int) ([]LiveValueTimeseries, string) {
+	switch algo {
+	case downsampleMinMax:
+		return minMaxDownsample(points, maxPoints), algo
+	case downsampleFirstLast:
+		return firstLastDownsample(points, maxPoints), algo
+	default:
+		return lttbDownsample(points, maxPoints), downsampleLTTB
+	}
+}
+
+// downsampleNotice reports the reduction from before to after, counted in
+// what, done by algo.
+func downsampleNotice(before, after int, what, algo string) data.Notice {
+	return data.Notice{
+		Severity: data.NoticeSeverityInfo,
+		Text: fmt.Sprintf("Downsampled from %d to %d %s (%.1f:1) using %s",
+			before, after, what, float64(before)/float64(after), algo),
+	}
+}
+
+// lttbDownsample implements Largest-Triangle-Three-Buckets, which keeps the
+// visual shape of a series with a fixed number of points. Nulls take no part
+// in the triangles, but the first null of a bucket is kept next to the chosen
+// point so that gaps and bad-quality stretches stay visible. When points
+// holds nulls, half as many buckets are used to leave room for them.
+func lttbDownsample(points []LiveValueTimeseries, threshold int) []LiveValueTimeseries {
+	n := len(points)
+	if threshold >= n {
+		return points
+	}
+	if threshold < 3 {
+		return firstLastDownsample(points, threshold)
+	}
+	inner := threshold - 2
+	if firstNull(points, 0, n) >= 0 {
+		inner /= 2
+	}
+	if inner < 1 {
+		return firstLastDownsample(points, threshold)
+	}
+
+	// x is the offset from the first sample, which keeps the float math
+	// precise enough for nanosecond timestamps.
+	t0 := points[0].Timestamp
+	x := func(i int) float64 { return float64(points[i].Timestamp.Sub(t0)) }
+
+	sampled := make([]LiveValueTimeseries, 0, threshold)
+	sampled = append(sampled, points[0])
+
+	bucketSize := float64(n-2) / float64(inner)
+	a := 0
+	for i := 0; i < inner; i++ {
+		// Average of the next bucket is the third triangle vertex.
+		avgStart := int(float64(i+1)*bucketSize) + 1
+		avgEnd := int(float64(i+2)*bucketSize) + 1
+		if avgEnd > n {
+			avgEnd = n
+		}
+		var avgX, avgY, cnt float64
+		for j := avgStart; j < avgEnd; j++ {
+			if points[j].Null {
+				continue
+			}
+			avgX += x(j)
+			avgY += points[j].Value
+			cnt++
+		}
+		if cnt > 0 {
+			avgX /= cnt
+			avgY /= cnt
+		}
+
+		// Pick the point of the current bucket forming the largest triangle.
+		start := int(float64(i)*bucketSize) + 1
+		end := int(float64(i+1)*bucketSize) + 1
+		maxArea, next := -1.0, -1
+		ax, ay := x(a), points[a].Value
+		for j := start; j < end; j++ {
+			if points[j].Null {
+				continue
+			}
+			area := math.Abs((ax-avgX)*(points[j].Value-ay) - (ax-x(j))*(avgY-ay))
+			if area > maxArea {
+				maxArea, next = area, j
+			}
+		}
+
+		sampled = appendInOrder(sampled, points, next, firstNull(points, start, end))
+		if next >= 0 {
+			a = next
+		}
+	}
+
+	return append(sampled, points[n-1])
+}
+
+// minMaxDownsample splits points into maxPoints/2 buckets and keeps the
+// minimum and maximum of each, preserving spikes. Nulls are kept as described
+// for bucketDownsample.
+func minMaxDownsample(points []LiveValueTimeseries, maxPoints int) []LiveValueTimeseries {
+	return bucketDownsample(points, maxPoints, func(bucket []LiveValueTimeseries) (int, int) {
+		lo, hi := 0, 0
+		for i, p := range bucket {
+			if p.Null {
+				continue
+			}
+			if bucket[lo].Null || p.Value < bucket[lo].Value {
+				lo = i
+			}
+			if bucket[hi].Null || p.Value > bucket[hi].Value {
+				hi = i
+			}
+		}
+		return lo, hi
+	})
+}
+
+// firstLastDownsample splits points into maxPoints/2 buckets and keeps the
+// first and last sample of each.
+func firstLastDownsample(points []LiveValueTimeseries, maxPoints int) []LiveValueTimeseries {
+	return bucketDownsample(points, maxPoints, func(bucket []LiveValueTimeseries) (int, int) {
+		return 0, len(bucket) - 1
+	})
+}
+
+// bucketDownsample splits points into equally sized buckets and keeps the two
+// samples chosen by pick from each, in time order. The first null of a bucket
+// is kept as well, so that gaps and bad-quality stretches stay visible; when
+// points holds nulls, a third of maxPoints buckets leaves room for them.
+func bucketDownsample(points []LiveValueTimeseries, maxPoints int, pick func([]LiveValueTimeseries) (int, int)) []LiveValueTimeseries {
+	buckets := maxPoints / 2
+	if firstNull(points, 0, len(points)) >= 0 {
+		buckets = maxPoints / 3
+	}
+	if buckets < 1 {
+		buckets = 1
+	}
+	if len(points) <= buckets {
+		return points
+	}
+
+	size := float64(len(points)) / float64(buckets)
+	out := make([]LiveValueTimeseries, 0, 2*buckets)
+	for b := 0; b < buckets; b++ {
+		start := int(float64(b) * size)
+		end := int(float64(b+1) * size)
+		if b == buckets-1 {
+			end = len(points)
+		}
+		bucket := points[start:end]
+
+		i, j := pick(bucket)
+		out = appendInOrder(out, bucket, i, j, firstNull(bucket, 0, len(bucket)))
+	}
+	return out
+}
+
+// firstNull returns the index of the first null in points[start:end], or -1.
+func firstNull(points []LiveValueTimeseries, start, end int) int {
+	for i := start; i < end; i++ {
+		if points[i].Null {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendInOrder appends the points at the given indices to out in time
+// order, skipping negative and repeated indices.
+func appendInOrder(out, points []LiveValueTimeseries, indices ...int) []LiveValueTimeseries {
+	sort.Ints(indices)
+	for k, i := range indices {
+		if i < 0 || (k > 0 && i == indices[k-1]) {
+			continue
+		}
+		out = append(out, points[i])
+	}
+	return out
+}
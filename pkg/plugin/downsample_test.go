@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDownsample(t *testing.T) {
+	wave := func(i int) float64 { return math.Sin(float64(i) / 10) }
+
+	for _, algo := range []string{"", downsampleLTTB, downsampleMinMax, downsampleFirstLast} {
+		s := &series{points: testPoints(1000, wave)}
+		s.downsample(algo, 100)
+
+		if len(s.points) > 100 {
+			t.Errorf("%q: got %d points, want at most 100", algo, len(s.points))
+		}
+		if len(s.notices) != 1 {
+			t.Errorf("%q: expected a reduction notice", algo)
+		}
+		for i := 1; i < len(s.points); i++ {
+			if !s.points[i].Timestamp.After(s.points[i-1].Timestamp) {
+				t.Fatalf("%q: points out of order at %d", algo, i)
+			}
+		}
+	}
+
+	s := &series{points: testPoints(1000, wave)}
+	s.downsample(downsampleNone, 100)
+	if len(s.points) != 1000 {
+		t.Errorf("none: got %d points, want 1000", len(s.points))
+	}
+}
+
+func TestMinMaxDownsampleKeepsSpikes(t *testing.T) {
+	points := testPoints(1000, func(i int) float64 {
+		if i == 517 {
+			return 100
+		}
+		return 0
+	})
+
+	out := minMaxDownsample(points, 50)
+	for _, p := range out {
+		if p.Value == 100 {
+			return
+		}
+	}
+	t.Fatal("spike was dropped")
+}
+
+func TestDownsampleKeepsQualityNulls(t *testing.T) {
+	wave := func(i int) float64 { return math.Sin(float64(i) / 10) }
+
+	points := testPoints(1000, wave)
+	first, last := points[400].Timestamp, points[409].Timestamp
+
+	for _, algo := range []string{downsampleLTTB, downsampleMinMax, downsampleFirstLast} {
+		s := &series{points: testPoints(1000, wave)}
+		for i := range s.points {
+			s.points[i].Quality = qualityCodeGood
+		}
+		for i := 400; i < 410; i++ {
+			s.points[i].Quality = 0
+		}
+		s.applyQuality(qualityModeNull)
+		s.downsample(algo, 100)
+		if len(s.points) > 100 {
+			t.Errorf("%s: len = %d, want <= 100", algo, len(s.points))
+		}
+
+		nulls := 0
+		for _, p := range s.points {
+			if !p.Null {
+				continue
+			}
+			nulls++
+			if p.Timestamp.Before(first) || p.Timestamp.After(last) {
+				t.Errorf("%s: null at %s outside the bad stretch", algo, p.Timestamp)
+			}
+		}
+		if nulls == 0 {
+			t.Errorf("%s: bad-quality stretch was downsampled away", algo)
+		}
+	}
+}
+
+func TestFillGapsThenDownsample(t *testing.T) {
+	// A 1000 second wave with a minute missing in the middle.
+	points := testPoints(1000, func(i int) float64 { return math.Sin(float64(i) / 10) })
+	points = append(points[:500:500], points[560:]...)
+	gapStart, gapEnd := points[499].Timestamp, points[500].Timestamp
+
+	for _, algo := range []string{downsampleLTTB, downsampleMinMax, downsampleFirstLast} {
+		s := &series{points: append([]LiveValueTimeseries(nil), points...)}
+		s.fillGaps(0, 2, gapFillNull, 0)
+		s.downsample(algo, 100)
+		if len(s.points) > 100 {
+			t.Errorf("%s: len = %d, want <= 100", algo, len(s.points))
+		}
+
+		marked := false
+		for _, p := range s.points {
+			if p.Null && p.Timestamp.After(gapStart) && p.Timestamp.Before(gapEnd) {
+				marked = true
+			}
+		}
+		if !marked {
+			t.Errorf("%s: gap marker was downsampled away", algo)
+		}
+	}
+}
+
+func TestDownsampleScatteredNullsStayWithinMaxPoints(t *testing.T) {
+	for _, algo := range []string{downsampleLTTB, downsampleMinMax, downsampleFirstLast} {
+		s := &series{points: testPoints(1000, func(i int) float64 { return math.Sin(float64(i) / 10) })}
+		for i := 0; i < len(s.points); i += 10 {
+			s.points[i].Null = true
+		}
+		s.downsample(algo, 100)
+		if len(s.points) > 100 {
+			t.Errorf("%s: len = %d, want <= 100", algo, len(s.points))
+		}
+		if firstNull(s.points, 0, len(s.points)) < 0 {
+			t.Errorf("%s: nulls were downsampled away", algo)
+		}
+	}
+}
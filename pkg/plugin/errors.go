@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/init/in-view/pkg/inview"
+)
+
+// errorResponse converts an InView client error into a data response.
+func errorResponse(err error) backend.DataResponse {
+	if msg, ok := contextError(err); ok {
+		log.DefaultLogger.Debug("PLUGIN QUERY -- Query stopped", "reason", msg)
+		return backend.ErrDataResponse(backend.StatusTimeout, msg)
+	}
+
+	log.DefaultLogger.Error("PLUGIN QUERY -- InView request failed", "error", err)
+
+	var apiErr *inview.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return backend.ErrDataResponseWithSource(backendStatus(apiErr.StatusCode), backend.ErrorSourceDownstream, apiErr.Error())
+	case errors.Is(err, inview.ErrRequestFailed):
+		return backend.ErrDataResponseWithSource(backend.StatusBadGateway, backend.ErrorSourceDownstream, "API request failed")
+	case errors.Is(err, inview.ErrInvalidResponse):
+		return backend.ErrDataResponse(backend.StatusInternal, "Failed to parse API response JSON")
+	default:
+		return backend.ErrDataResponse(backend.StatusInternal, err.Error())
+	}
+}
+
+// backendStatus maps an InView HTTP status onto the closest backend.Status.
+func backendStatus(code int) backend.Status {
+	switch {
+	case code == http.StatusUnauthorized:
+		return backend.StatusUnauthorized
+	case code == http.StatusForbidden:
+		return backend.StatusForbidden
+	case code == http.StatusNotFound:
+		return backend.StatusNotFound
+	case code == http.StatusTooManyRequests:
+		return backend.StatusTooManyRequests
+	case code == http.StatusRequestTimeout, code == http.StatusGatewayTimeout:
+		return backend.StatusTimeout
+	case code == http.StatusUnprocessableEntity:
+		return backend.StatusValidationFailed
+	case code == http.StatusNotImplemented:
+		return backend.StatusNotImplemented
+	case code >= 500:
+		return backend.StatusBadGateway
+	default:
+		return backend.StatusBadRequest
+	}
+}
+
+// contextError reports whether err was caused by the request context ending,
+// along with a message suitable for the user.
+func contextError(err error) (string, bool) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "query canceled", true
+	case errors.Is(err, context.DeadlineExceeded):
+		return "query timed out", true
+	default:
+		return "", false
+	}
+}
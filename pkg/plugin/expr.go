@@ -0,0 +1,514 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expression is a parsed math expression over InView variables, e.g.
+// "$12 - $13", "${Flow Rate} * 24" or "clamp($level, 0, 100)". Variables are
+// referenced by ID or by name or alias. Comparisons yield 1 or 0, and any
+// non-zero value is true in if().
+type expression struct {
+	root exprNode
+	refs []*refNode
+}
+
+// ids returns the IDs of the variables referenced by e.
+func (e *expression) ids() []int {
+	seen := make(map[int]bool, len(e.refs))
+	var ids []int
+	for _, r := range e.refs {
+		if !seen[r.id] {
+			seen[r.id] = true
+			ids = append(ids, r.id)
+		}
+	}
+	return ids
+}
+
+// eval evaluates e with the variable values returned by value. The result
+// is not ok when a variable the result depends on is null or the arithmetic
+// is undefined.
+func (e *expression) eval(value func(id int) (float64, bool)) (float64, bool) {
+	v, ok := e.root.eval(value)
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+type exprNode interface {
+	eval(value func(id int) (float64, bool)) (float64, bool)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(func(int) (float64, bool)) (float64, bool) {
+	return float64(n), true
+}
+
+// refNode is a variable reference. name is the reference as written and id
+// the variable it resolves to.
+type refNode struct {
+	name string
+	id   int
+}
+
+func (r *refNode) eval(value func(int) (float64, bool)) (float64, bool) {
+	return value(r.id)
+}
+
+type unaryNode struct {
+	x exprNode
+}
+
+func (u unaryNode) eval(value func(int) (float64, bool)) (float64, bool) {
+	x, ok := u.x.eval(value)
+	return -x, ok
+}
+
+type binaryNode struct {
+	op   string
+	l, r exprNode
+}
+
+func (b binaryNode) eval(value func(int) (float64, bool)) (float64, bool) {
+	l, ok := b.l.eval(value)
+	if !ok {
+		return 0, false
+	}
+	r, ok := b.r.eval(value)
+	if !ok {
+		return 0, false
+	}
+
+	switch b.op {
+	case "+":
+		return l + r, true
+	case "-":
+		return l - r, true
+	case "*":
+		return l * r, true
+	case "/":
+		return l / r, r != 0
+	case "%":
+		return math.Mod(l, r), r != 0
+	case "^":
+		return math.Pow(l, r), true
+	case "<":
+		return boolValue(l < r), true
+	case "<=":
+		return boolValue(l <= r), true
+	case ">":
+		return boolValue(l > r), true
+	case ">=":
+		return boolValue(l >= r), true
+	case "==":
+		return boolValue(l == r), true
+	default: // "!="
+		return boolValue(l != r), true
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type callNode struct {
+	fn   string
+	args []exprNode
+}
+
+func (c callNode) eval(value func(int) (float64, bool)) (float64, bool) {
+	// if only evaluates the branch it takes, so a null in the other branch
+	// does not null the result.
+	if c.fn == "if" {
+		cond, ok := c.args[0].eval(value)
+		if !ok {
+			return 0, false
+		}
+		if cond != 0 {
+			return c.args[1].eval(value)
+		}
+		return c.args[2].eval(value)
+	}
+
+	args := make([]float64, len(c.args))
+	for i, a := range c.args {
+		v, ok := a.eval(value)
+		if !ok {
+			return 0, false
+		}
+		args[i] = v
+	}
+
+	switch c.fn {
+	case "abs":
+		return math.Abs(args[0]), true
+	case "min":
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Min(m, a)
+		}
+		return m, true
+	case "max":
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Max(m, a)
+		}
+		return m, true
+	default: // "clamp"
+		return math.Max(args[1], math.Min(args[2], args[0])), true
+	}
+}
+
+// exprFuncs maps the supported functions to their argument count; -1 means
+// one or more.
+var exprFuncs = map[string]int{
+	"abs":   1,
+	"min":   -1,
+	"max":   -1,
+	"clamp": 3,
+	"if":    3,
+}
+
+// parseExpression parses src. Variable references are left unresolved.
+func parseExpression(src string) (*expression, error) {
+	p := &exprParser{src: src}
+	p.next()
+	root, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	return &expression{root: root, refs: p.refs}, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokRef
+	tokIdent
+	tokOp
+	tokInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type exprParser struct {
+	src  string
+	pos  int
+	tok  token
+	refs []*refNode
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("expression: "+format+" at position %d", append(args, p.tok.pos+1)...)
+}
+
+// next advances to the next token.
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case isDigit(c) || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		// Exponent, e.g. 1e-3.
+		if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			end := p.pos + 1
+			if end < len(p.src) && (p.src[end] == '+' || p.src[end] == '-') {
+				end++
+			}
+			if end < len(p.src) && isDigit(p.src[end]) {
+				for p.pos = end; p.pos < len(p.src) && isDigit(p.src[p.pos]); p.pos++ {
+				}
+			}
+		}
+		p.tok = token{kind: tokNumber, text: p.src[start:p.pos], pos: start}
+	case c == '$':
+		p.pos++
+		if p.pos < len(p.src) && p.src[p.pos] == '{' {
+			end := strings.IndexByte(p.src[p.pos:], '}')
+			if end < 0 {
+				p.tok = token{kind: tokInvalid, text: p.src[start:], pos: start}
+				p.pos = len(p.src)
+				return
+			}
+			p.tok = token{kind: tokRef, text: strings.TrimSpace(p.src[p.pos+1 : p.pos+end]), pos: start}
+			p.pos += end + 1
+			return
+		}
+		for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokRef, text: p.src[start+1 : p.pos], pos: start}
+	case isIdentChar(c):
+		for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: strings.ToLower(p.src[start:p.pos]), pos: start}
+	default:
+		p.pos++
+		if p.pos < len(p.src) && p.src[p.pos] == '=' && strings.IndexByte("<>=!", c) >= 0 {
+			p.pos++
+		}
+		op := p.src[start:p.pos]
+		kind := tokOp
+		if !strings.Contains(" + - * / % ^ ( ) , < > <= >= == != ", " "+op+" ") {
+			kind = tokInvalid
+		}
+		p.tok = token{kind: kind, text: op, pos: start}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
+
+func (p *exprParser) isOp(ops ...string) bool {
+	if p.tok.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComparison parses an optional comparison of two sums, the lowest
+// precedence level.
+func (p *exprParser) parseComparison() (exprNode, error) {
+	l, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("<", "<=", ">", ">=", "==", "!=") {
+		op := p.tok.text
+		p.next()
+		r, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: op, l: l, r: r}, nil
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	l, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.tok.text
+		p.next()
+		r, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/", "%") {
+		op := p.tok.text
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.isOp("-", "+") {
+		neg := p.tok.text == "-"
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil || !neg {
+			return x, err
+		}
+		return unaryNode{x: x}, nil
+	}
+	return p.parsePower()
+}
+
+// parsePower parses a right-associative power, e.g. 2^3^2 is 2^9.
+func (p *exprParser) parsePower() (exprNode, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("^") {
+		p.next()
+		exp, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: "^", l: base, r: exp}, nil
+	}
+	return base, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok.text)
+		}
+		p.next()
+		return numberNode(v), nil
+	case tokRef:
+		if tok.text == "" {
+			return nil, p.errorf("empty variable reference")
+		}
+		ref := &refNode{name: tok.text}
+		p.refs = append(p.refs, ref)
+		p.next()
+		return ref, nil
+	case tokIdent:
+		return p.parseCall()
+	case tokOp:
+		if tok.text == "(" {
+			p.next()
+			x, err := p.parseComparison()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOp(")") {
+				return nil, p.errorf("expected )")
+			}
+			p.next()
+			return x, nil
+		}
+	case tokEOF:
+		return nil, p.errorf("unexpected end")
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+func (p *exprParser) parseCall() (exprNode, error) {
+	fn := p.tok.text
+	arity, ok := exprFuncs[fn]
+	if !ok {
+		return nil, p.errorf("unknown function %q", fn)
+	}
+	p.next()
+	if !p.isOp("(") {
+		return nil, p.errorf("expected ( after %s", fn)
+	}
+	p.next()
+
+	var args []exprNode
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if !p.isOp(",") {
+				return nil, p.errorf("expected , or )")
+			}
+			p.next()
+		}
+		arg, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+
+	if (arity < 0 && len(args) == 0) || (arity >= 0 && len(args) != arity) {
+		return nil, fmt.Errorf("expression: %s takes %s", fn, arityText(arity))
+	}
+	return callNode{fn: fn, args: args}, nil
+}
+
+func arityText(arity int) string {
+	switch arity {
+	case -1:
+		return "one or more arguments"
+	case 1:
+		return "one argument"
+	default:
+		return fmt.Sprintf("%d arguments", arity)
+	}
+}
+
+// expression parses the query's Expression and resolves its variable
+// references. It returns nil when the query has no expression.
+func (qm queryModel) expression() (*expression, error) {
+	if strings.TrimSpace(qm.Expression) == "" {
+		return nil, nil
+	}
+	e, err := parseExpression(qm.Expression)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range e.refs {
+		if r.id, err = qm.resolveRef(r.name); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// resolveRef resolves a variable reference: a variable ID, an alias from
+// Aliases or the name of a selected variable, ignoring case.
+func (qm queryModel) resolveRef(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	if id, ok := qm.Aliases[name]; ok {
+		return id, nil
+	}
+	for alias, id := range qm.Aliases {
+		if strings.EqualFold(alias, name) {
+			return id, nil
+		}
+	}
+	for _, v := range qm.Variables {
+		if strings.EqualFold(v.VariableName, name) {
+			return v.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("expression: unknown variable %q", name)
+}
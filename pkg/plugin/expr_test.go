@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/init/in-view/pkg/inview"
+)
+
+func TestExpression(t *testing.T) {
+	qm := queryModel{
+		Variables: []inview.Variables{{ID: 1, VariableName: "P1"}, {ID: 2, VariableName: "Flow Rate"}},
+		Aliases:   map[string]int{"level": 3},
+	}
+	values := map[int]float64{1: 10, 2: 4, 3: 150}
+	value := func(id int) (float64, bool) {
+		v, ok := values[id]
+		return v, ok
+	}
+
+	for src, want := range map[string]float64{
+		"$1 - $2":                     6,
+		"$p1 * 24":                    240,
+		"${Flow Rate} ^ 2 / 2":        8,
+		"-$1 + 2 * (3 - 1)":           -6,
+		"abs($2 - $1)":                6,
+		"max($1, $2, 7)":              10,
+		"clamp($level, 0, 100)":       100,
+		"if($1 > $2, 1, $99)":         1,
+		"if($1 >= 11, 1, 0) + $2 % 3": 1,
+		"min(1e1, 2.5E+1) == 10":      1,
+	} {
+		qm.Expression = src
+		e, err := qm.expression()
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		if got, ok := e.eval(value); !ok || got != want {
+			t.Errorf("%q = %v (ok %v), want %v", src, got, ok, want)
+		}
+	}
+
+	for _, src := range []string{"$1 +", "foo($1)", "clamp($1)", "$1 & $2", "${Flow Rate", "$unknown"} {
+		qm.Expression = src
+		if _, err := qm.expression(); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+
+	qm.Expression = "$1 / ($2 - 4)"
+	if e, _ := qm.expression(); e != nil {
+		if _, ok := e.eval(value); ok {
+			t.Error("division by zero should be null")
+		}
+	}
+}
+
+func TestExpressionSeries(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(sec int, v float64) LiveValueTimeseries {
+		return LiveValueTimeseries{Timestamp: t0.Add(time.Duration(sec) * time.Second), Value: v, Quality: qualityCodeGood}
+	}
+	// The variables log at different instants, well outside the tolerance.
+	a := &series{id: 1, name: "A", points: []LiveValueTimeseries{at(0, 10), at(10, 20), at(20, 30)}}
+	b := &series{id: 2, name: "B", points: []LiveValueTimeseries{at(5, 1), at(15, 2)}}
+
+	qm := queryModel{
+		Variables:  []inview.Variables{{ID: 1, VariableName: "A"}, {ID: 2, VariableName: "B"}},
+		Expression: "$1 - $2",
+	}
+	e, err := qm.expression()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows at 0, 5, 10, 15 and 20s: B has no value yet at 0s, then both
+	// hold their last value.
+	s := qm.expressionSeries(e, []*series{a, b}, 0)
+	if len(s.points) != 5 || !s.points[0].Null {
+		t.Fatalf("got %+v", s.points)
+	}
+	for i, want := range []float64{9, 19, 18, 28} {
+		if p := s.points[i+1]; p.Null || p.Value != want {
+			t.Errorf("row %d = %v (null %v), want %v", i+1, p.Value, p.Null, want)
+		}
+	}
+
+	// An explicit null fill only evaluates rows where all variables logged.
+	qm.AlignFill = alignFillNull
+	s = qm.expressionSeries(e, []*series{a, b}, 0)
+	for i, p := range s.points {
+		if !p.Null {
+			t.Errorf("null fill: row %d = %v, want null", i, p.Value)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+)
+
+// Alarm states.
+const (
+	alarmActive  = "Active"
+	alarmCleared = "Cleared"
+)
+
+// alarmsFrame builds the alarms table. Timestamps are wall-clock times in loc;
+// the ones that cannot be parsed are null and reported in a notice. An alarm
+// without a termination time is active: its termination time is null and
+// its duration is measured to now. With activeOnly, cleared alarms are left
+// out.
+func alarmsFrame(ctx context.Context, raw []inview.AlarmLog, loc *time.Location, now time.Time, activeOnly bool) (*data.Frame, error) {
+	frame := data.NewFrame(
+		"Alarms",
+		data.NewField("Description", nil, []string{}),
+		data.NewField("Activation Time", nil, []*time.Time{}),
+		data.NewField("Termination Time", nil, []*time.Time{}),
+		data.NewField("Duration", nil, []*float64{}).SetConfig(&data.FieldConfig{Unit: "s"}),
+		data.NewField("State", nil, []string{}),
+	)
+
+	times := timestampParser{loc: loc}
+	for _, alarm := range raw {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		state := alarmCleared
+		if alarm.IwsAlarmTerminationTime == "" {
+			state = alarmActive
+		}
+		if activeOnly && state != alarmActive {
+			continue
+		}
+
+		activation := times.parse(alarm.IwsAlarmActivationTime)
+		termination := times.parse(alarm.IwsAlarmTerminationTime)
+
+		var duration *float64
+		end := termination
+		if state == alarmActive {
+			end = &now
+		}
+		if activation != nil && end != nil {
+			d := end.Sub(*activation).Seconds()
+			duration = &d
+		}
+
+		frame.AppendRow(alarm.IwsAlarmDescription, activation, termination, duration, state)
+	}
+
+	frame.AppendNotices(times.notices()...)
+	return frame, nil
+}
+
+// eventsFrame builds the events table. Timestamps are wall-clock times in loc;
+// the ones that cannot be parsed are null and reported in a notice.
+func eventsFrame(ctx context.Context, raw []inview.EventLog, loc *time.Location) (*data.Frame, error) {
+	frame := data.NewFrame(
+		"Events",
+		data.NewField("Description", nil, []string{}),
+		data.NewField("Activation Time", nil, []*time.Time{}),
+	)
+
+	times := timestampParser{loc: loc}
+	for _, event := range raw {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		frame.AppendRow(event.IwsEventDescription, times.parse(event.IwsEventTimestamp))
+	}
+
+	frame.AppendNotices(times.notices()...)
+	return frame, nil
+}
+
+// appendNotice attaches n to the first frame of a response so that it is shown
+// once per query.
+func appendNotice(frames []*data.Frame, n data.Notice) {
+	if len(frames) == 0 {
+		return
+	}
+	frames[0].AppendNotices(n)
+}
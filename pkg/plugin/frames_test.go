@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/init/in-view/pkg/inview"
+)
+
+func TestAlarmsFrame(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	raw := []inview.AlarmLog{
+		{IwsAlarmDescription: "Cleared", IwsAlarmActivationTime: "2024-01-01T10:00:00", IwsAlarmTerminationTime: "2024-01-01T10:00:30"},
+		{IwsAlarmDescription: "Standing", IwsAlarmActivationTime: "2024-01-01T11:00:00"},
+	}
+
+	frame, err := alarmsFrame(context.Background(), raw, time.UTC, now, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := frame.Fields[3].ConcreteAt(0); d != 30.0 {
+		t.Errorf("cleared duration = %v, want 30", d)
+	}
+	if _, ok := frame.Fields[2].ConcreteAt(1); ok {
+		t.Error("active alarm should have a null termination time")
+	}
+	if d, _ := frame.Fields[3].ConcreteAt(1); d != 3600.0 {
+		t.Errorf("active duration = %v, want 3600", d)
+	}
+	if s := frame.Fields[4].At(1); s != alarmActive {
+		t.Errorf("state = %v, want %s", s, alarmActive)
+	}
+
+	frame, err = alarmsFrame(context.Background(), raw, time.UTC, now, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := frame.RowLen(); rows != 1 {
+		t.Errorf("active only: got %d rows, want 1", rows)
+	}
+}
+
+func TestAlarmKPIFrames(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	now := from.Add(48 * time.Hour)
+
+	var raw []inview.AlarmLog
+	// A flood of 12 chattering alarms in the first window.
+	for i := 0; i < 12; i++ {
+		at := from.Add(time.Duration(i) * 30 * time.Second)
+		raw = append(raw, inview.AlarmLog{
+			IwsAlarmDescription:     "Pump trip",
+			IwsAlarmActivationTime:  at.Format("2006-01-02T15:04:05"),
+			IwsAlarmTerminationTime: at.Add(10 * time.Second).Format("2006-01-02T15:04:05"),
+		})
+	}
+	// One standing alarm.
+	raw = append(raw, inview.AlarmLog{IwsAlarmDescription: "Tank high", IwsAlarmActivationTime: "2024-01-01T00:30:00"})
+
+	frames := alarmKPIFrames(queryModel{}, raw, from, to, time.UTC, now)
+	if len(frames) != 5 {
+		t.Fatalf("got %d frames, want 5", len(frames))
+	}
+
+	summary := map[string]float64{}
+	for i := 0; i < frames[0].Fields[0].Len(); i++ {
+		summary[frames[0].Fields[0].At(i).(string)] = frames[0].Fields[1].At(i).(float64)
+	}
+	want := map[string]float64{
+		"Total alarms": 13,
+		"Peak alarm rate per operator per 10 min": 12,
+		"Chattering alarms":                       1,
+		"Standing alarms":                         1,
+		"Stale alarms":                            1,
+	}
+	for k, v := range want {
+		if summary[k] != v {
+			t.Errorf("%s = %v, want %v", k, summary[k], v)
+		}
+	}
+	// One of six windows is flooded.
+	if got := summary["Time in flood (%)"]; math.Abs(got-100.0/6) > 1e-9 {
+		t.Errorf("time in flood = %v", got)
+	}
+	if top := frames[2].Fields[0].At(0); top != "Pump trip" {
+		t.Errorf("top alarm = %v", top)
+	}
+}
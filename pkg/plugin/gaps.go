@@ -0,0 +1,134 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/models"
+)
+
+// Fill modes for gaps in history series.
+const (
+	gapFillNull     = "null"
+	gapFillPrevious = "previous"
+	gapFillLinear   = "linear"
+	gapFillZero     = "zero"
+	gapFillConstant = "constant"
+)
+
+// maxGapFillPoints caps the points inserted into a single series. Gaps past
+// the cap are only marked with a null.
+const maxGapFillPoints = 10000
+
+func validGapFill(fill string) bool {
+	switch fill {
+	case "", gapFillNull, gapFillPrevious, gapFillLinear, gapFillZero, gapFillConstant:
+		return true
+	default:
+		return false
+	}
+}
+
+// gapThreshold parses GapThreshold. Zero means no fixed threshold.
+func (qm queryModel) gapThreshold() (time.Duration, error) {
+	if qm.GapThreshold == "" {
+		return 0, nil
+	}
+	d, err := models.ParseDuration(qm.GapThreshold)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid gap threshold %q", qm.GapThreshold)
+	}
+	return d, nil
+}
+
+// loggingInterval estimates the logging rate of points as the median time
+// between consecutive samples.
+func loggingInterval(points []LiveValueTimeseries) time.Duration {
+	if len(points) < 2 {
+		return 0
+	}
+	deltas := make([]time.Duration, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		deltas = append(deltas, points[i].Timestamp.Sub(points[i-1].Timestamp))
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i] < deltas[j] })
+	return deltas[len(deltas)/2]
+}
+
+// fillGaps finds the gaps of s longer than threshold, or factor times the
+// logging interval when threshold is zero, and fills them at the logging
+// interval as selected by fill. The null mode inserts a single null right
+// after the last sample before each gap, which is enough to break the line
+// and is kept by downsampling.
+func (s *series) fillGaps(threshold time.Duration, factor float64, fill string, constant float64) {
+	step := loggingInterval(s.points)
+	if step <= 0 {
+		return
+	}
+	if threshold == 0 {
+		threshold = time.Duration(factor * float64(step))
+	}
+	if threshold <= 0 {
+		return
+	}
+
+	out := make([]LiveValueTimeseries, 0, len(s.points))
+	gaps, inserted := 0, 0
+	for i, p := range s.points {
+		if i > 0 {
+			prev := s.points[i-1]
+			if gap := p.Timestamp.Sub(prev.Timestamp); gap > threshold {
+				gaps++
+				n := int((gap - 1) / step)
+				if fill == "" || fill == gapFillNull || inserted+n > maxGapFillPoints {
+					n = 1
+					offset := min(step, gap/2)
+					out = append(out, LiveValueTimeseries{Timestamp: prev.Timestamp.Add(offset), Quality: prev.Quality, Null: true})
+				} else {
+					for k := 1; k <= n; k++ {
+						out = append(out, gapPoint(prev, p, prev.Timestamp.Add(time.Duration(k)*step), fill, constant))
+					}
+				}
+				inserted += n
+			}
+		}
+		out = append(out, p)
+	}
+	if gaps == 0 {
+		return
+	}
+
+	s.points = out
+	s.notices = append(s.notices, data.Notice{
+		Severity: data.NoticeSeverityInfo,
+		Text:     fmt.Sprintf("Found %d gap(s) longer than %s, filled with %d %s point(s)", gaps, threshold, inserted, gapFillName(fill)),
+	})
+}
+
+// gapPoint returns the point at t inside the gap between prev and next.
+func gapPoint(prev, next LiveValueTimeseries, t time.Time, fill string, constant float64) LiveValueTimeseries {
+	p := LiveValueTimeseries{Timestamp: t, Quality: prev.Quality}
+	switch fill {
+	case gapFillPrevious:
+		p.Value, p.Null = prev.Value, prev.Null
+	case gapFillLinear:
+		if prev.Null || next.Null {
+			p.Null = true
+			break
+		}
+		frac := float64(t.Sub(prev.Timestamp)) / float64(next.Timestamp.Sub(prev.Timestamp))
+		p.Value = prev.Value + frac*(next.Value-prev.Value)
+	case gapFillConstant:
+		p.Value = constant
+	}
+	return p
+}
+
+func gapFillName(fill string) string {
+	if fill == "" {
+		return gapFillNull
+	}
+	return fill
+}
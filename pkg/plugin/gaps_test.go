@@ -0,0 +1,37 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFillGaps(t *testing.T) {
+	// Ten samples one second apart with samples 3 to 6 missing.
+	withGap := func() []LiveValueTimeseries {
+		p := testPoints(10, func(i int) float64 { return float64(i) })
+		return append(p[:3:3], p[7:]...)
+	}
+
+	s := &series{points: withGap()}
+	s.fillGaps(0, 2, gapFillNull, 0)
+	if len(s.points) != 7 || !s.points[3].Null {
+		t.Fatalf("null: got %+v", s.points)
+	}
+
+	s = &series{points: withGap()}
+	s.fillGaps(2*time.Second, 0, gapFillLinear, 0)
+	if len(s.points) != 10 {
+		t.Fatalf("linear: got %d points, want 10", len(s.points))
+	}
+	for i, p := range s.points {
+		if p.Value != float64(i) {
+			t.Errorf("linear: point %d = %v, want %d", i, p.Value, i)
+		}
+	}
+
+	s = &series{points: withGap()}
+	s.fillGaps(0, 2, gapFillConstant, -1)
+	if s.points[4].Value != -1 || len(s.notices) != 1 {
+		t.Errorf("constant: got %+v", s.points)
+	}
+}
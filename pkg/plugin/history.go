@@ -0,0 +1,196 @@
+package plugin
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+)
+
+// timeWindow is a half-open [From, To) slice of a query time range.
+type timeWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+// splitTimeRange cuts [from, to) into consecutive windows of at most size.
+// A non-positive size returns the whole range as a single window.
+func splitTimeRange(from, to time.Time, size time.Duration) []timeWindow {
+	if size <= 0 || !to.After(from) {
+		return []timeWindow{{From: from, To: to}}
+	}
+
+	var windows []timeWindow
+	for start := from; start.Before(to); start = start.Add(size) {
+		end := start.Add(size)
+		if end.After(to) {
+			end = to
+		}
+		windows = append(windows, timeWindow{From: start, To: end})
+	}
+	return windows
+}
+
+// fetchHistory fetches the logged values of ids between from and to. Long
+// ranges are split into windows of the configured chunk size which are
+// fetched concurrently and merged back in chronological order. Samples that
+// appear in two adjacent windows are kept once.
+func (d *Datasource) fetchHistory(ctx context.Context, from, to time.Time, ids []int) ([]inview.RawLiveValue, error) {
+	windows := splitTimeRange(from, to, d.settings.HistoryChunkDuration)
+	chunks := make([][]inview.RawLiveValue, len(windows))
+
+	err := forEachLimit(ctx, len(windows), d.settings.HistoryChunkConcurrency, func(ctx context.Context, i int) error {
+		raw, err := d.client.GetHistory(ctx, inview.HistoryOptions{
+			From:        windows[i].From,
+			To:          windows[i].To,
+			VariableIDs: ids,
+		})
+		if err != nil {
+			return err
+		}
+		chunks[i] = raw
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 1 {
+		return chunks[0], nil
+	}
+
+	type sampleKey struct {
+		id int
+		ts string
+	}
+	seen := make(map[sampleKey]struct{})
+
+	var merged []inview.RawLiveValue
+	for _, chunk := range chunks {
+		for _, r := range chunk {
+			k := sampleKey{id: r.VariableId, ts: r.Timestamp}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			merged = append(merged, r)
+		}
+	}
+	return merged, nil
+}
+
+// historyFrames turns the raw history into one frame per variable, or a
+// single wide frame when the query asks for it. The query's expression is
+// one more series: its own frame, or another value field of the wide frame.
+// The value fields are configured from the catalog entries of the variables.
+func (d *Datasource) historyFrames(ctx context.Context, qm queryModel, query backend.DataQuery, raw []inview.RawLiveValue) ([]*data.Frame, error) {
+	list, err := groupSeries(ctx, raw, qm.Variables, d.settings.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, _ := qm.bucketSize(query)
+	unit, _ := qm.transformUnit()
+	gapThreshold, _ := qm.gapThreshold()
+	tolerance, _ := qm.alignTolerance()
+	expr, _ := qm.expression()
+	catalog := d.catalog.lookup(ctx)
+
+	for _, s := range list {
+		s.labels = seriesLabels(s)
+		s.config = fieldConfig(s.name, catalog[s.id], qm.changesUnit(), qm.changesRange())
+		s.applyQuality(qm.QualityMode)
+		s.transform(qm.Transform, unit, bucket, d.settings.Location, qm.CounterMax)
+		s.aggregate(qm.Aggregation, bucket, d.settings.Location)
+		if qm.detectGaps() {
+			s.fillGaps(gapThreshold, qm.GapFactor, qm.GapFill, qm.GapFillValue)
+		}
+	}
+
+	// The expression is evaluated before downsampling, which would leave
+	// the series with samples that no longer line up.
+	if expr != nil {
+		exprSeries := qm.expressionSeries(expr, list, tolerance)
+		list = append(qm.selectedSeries(list), exprSeries)
+	}
+
+	var frames []*data.Frame
+	if qm.OutputFormat == outputWide {
+		frames = append(frames, wideFrame(list, tolerance, qm.AlignFill, qm.ShowQuality, qm.Downsample, int(query.MaxDataPoints)))
+	} else {
+		for _, s := range list {
+			s.downsample(qm.Downsample, int(query.MaxDataPoints))
+			frames = append(frames, s.frame(qm.ShowQuality))
+		}
+	}
+	return frames, nil
+}
+
+// expressionSeries evaluates expr over the aligned rows of list. Variables
+// hold their last value until their next sample unless the query selects
+// another fill, since variables rarely log at the same instants. A row is
+// null when a variable the result depends on is null or has no value yet, and
+// carries the worst quality of the variables it was computed from.
+func (qm queryModel) expressionSeries(expr *expression, list []*series, tolerance time.Duration) *series {
+	name := qm.ExpressionName
+	if name == "" {
+		name = "Expression"
+	}
+	out := &series{
+		name:   name,
+		labels: data.Labels{"expression": qm.Expression},
+		config: &data.FieldConfig{DisplayNameFromDS: name},
+	}
+
+	fill := qm.AlignFill
+	if fill == "" {
+		fill = alignFillPrevious
+	}
+	rows, columns := alignSeries(list, tolerance, fill)
+	column := make(map[int][]wideCell, len(list))
+	for k, s := range list {
+		column[s.id] = columns[k]
+	}
+
+	out.points = make([]LiveValueTimeseries, len(rows))
+	for i, t := range rows {
+		p := LiveValueTimeseries{Timestamp: t, Quality: qualityCodeGood}
+		v, ok := expr.eval(func(id int) (float64, bool) {
+			cells, ok := column[id]
+			if !ok || !cells[i].set || cells[i].null {
+				return 0, false
+			}
+			p.Quality = min(p.Quality, cells[i].quality)
+			return cells[i].value, true
+		})
+		p.Value, p.Null = v, !ok
+		out.points[i] = p
+	}
+	return out
+}
+
+// selectedSeries drops the series that were only fetched for the expression.
+func (qm queryModel) selectedSeries(list []*series) []*series {
+	selected := qm.variableIDs()
+	kept := list[:0]
+	for _, s := range list {
+		if slices.Contains(selected, s.id) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// seriesLabels returns the dimensions of s. The variable catalog carries no
+// connection or location, and the query's own selection would label every
+// series alike, so only the variable is used.
+func seriesLabels(s *series) data.Labels {
+	return data.Labels{
+		"variable_id":   strconv.Itoa(s.id),
+		"variable_name": s.name,
+	}
+}
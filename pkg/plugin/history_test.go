@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+)
+
+func TestFetchHistoryChunks(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		// Every window returns its own start plus a sample shared with the
+		// neighbouring window.
+		from := r.URL.Query().Get("dateFrom")
+		_, _ = w.Write([]byte(`[{"VariableId":1,"Value":1,"timestamp":"` + from + `"},{"VariableId":1,"Value":2,"timestamp":"2024-01-01T00:00:00"}]`))
+	}))
+	defer srv.Close()
+
+	ds := newTestDatasource(t, srv.URL, `"historyChunk":"1d"`)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	raw, err := ds.fetchHistory(context.Background(), from, from.Add(72*time.Hour), []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("calls = %d, want 3", calls)
+	}
+	// Three window starts, one of which is the shared sample.
+	if len(raw) != 3 {
+		t.Fatalf("len(raw) = %d, want 3: %+v", len(raw), raw)
+	}
+}
+
+func TestSeriesLabels(t *testing.T) {
+	labels := seriesLabels(&series{id: 7, name: "Tank level"})
+	want := data.Labels{"variable_id": "7", "variable_name": "Tank level"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("labels = %v, want %v", labels, want)
+	}
+}
+
+func TestHistoryFramesWideExpression(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	ds := newTestDatasource(t, srv.URL)
+	qm := queryModel{
+		Variables:    []inview.Variables{{ID: 1, VariableName: "A"}, {ID: 2, VariableName: "B"}},
+		Expression:   "$1 + $2",
+		OutputFormat: outputWide,
+	}
+	raw := []inview.RawLiveValue{
+		{VariableId: 1, Value: 1, Timestamp: "2024-01-01T00:00:00"},
+		{VariableId: 2, Value: 2, Timestamp: "2024-01-01T00:00:00"},
+	}
+	frames, err := ds.historyFrames(context.Background(), qm, backend.DataQuery{}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 1 || frames[0].Meta.Type != data.FrameTypeTimeSeriesWide {
+		t.Fatalf("got %d frames, want a single wide frame", len(frames))
+	}
+	// The time field and a value field per variable and the expression.
+	if n := len(frames[0].Fields); n != 4 {
+		t.Fatalf("got %d fields, want 4", n)
+	}
+	if v, _ := frames[0].Fields[3].ConcreteAt(0); v != 3.0 {
+		t.Errorf("expression = %v, want 3", v)
+	}
+}
@@ -0,0 +1,258 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+	"github.com/init/in-view/pkg/models"
+)
+
+// ISA-18.2 defaults for the alarm KPI mode.
+const (
+	// kpiRateWindow is the window alarm rates are counted over.
+	kpiRateWindow = 10 * time.Minute
+	// defaultFloodThreshold is the alarms per operator per window above
+	// which the operator is flooded.
+	defaultFloodThreshold = 10
+	// defaultChatterSeconds is how soon an alarm must re-activate after it
+	// cleared to count as chattering.
+	defaultChatterSeconds = 60
+	// defaultStaleAfter is how long an alarm must stand to be stale.
+	defaultStaleAfter = 24 * time.Hour
+	// kpiTopAlarms is the number of most frequent alarms listed.
+	kpiTopAlarms = 10
+)
+
+// alarmKPIOptions are the query's KPI parameters with defaults applied.
+type alarmKPIOptions struct {
+	operators      int
+	floodThreshold float64
+	chatter        time.Duration
+	staleAfter     time.Duration
+}
+
+func (qm queryModel) alarmKPIOptions() (alarmKPIOptions, error) {
+	opts := alarmKPIOptions{
+		operators:      max(qm.Operators, 1),
+		floodThreshold: defaultFloodThreshold,
+		chatter:        defaultChatterSeconds * time.Second,
+		staleAfter:     defaultStaleAfter,
+	}
+	if qm.FloodThreshold > 0 {
+		opts.floodThreshold = qm.FloodThreshold
+	}
+	if qm.ChatterSeconds > 0 {
+		opts.chatter = time.Duration(qm.ChatterSeconds) * time.Second
+	}
+	if qm.StaleAfter != "" {
+		d, err := models.ParseDuration(qm.StaleAfter)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("invalid stale after %q", qm.StaleAfter)
+		}
+		opts.staleAfter = d
+	}
+	return opts, nil
+}
+
+// alarmRecord is an alarm log row with parsed times.
+type alarmRecord struct {
+	description string
+	activation  time.Time
+	termination *time.Time
+	active      bool
+}
+
+// alarmKPIFrames computes ISA-18.2 alarm performance metrics over [from, to)
+// from the alarm log. It returns a summary table, the alarm rate per
+// operator and window as a time series, and tables of the most frequent,
+// chattering and standing alarms. Rows without a readable activation time
+// are left out and reported in a notice.
+func alarmKPIFrames(qm queryModel, raw []inview.AlarmLog, from, to time.Time, loc *time.Location, now time.Time) []*data.Frame {
+	opts, _ := qm.alarmKPIOptions()
+
+	times := timestampParser{loc: loc}
+	records := make([]alarmRecord, 0, len(raw))
+	for _, a := range raw {
+		activation := times.parse(a.IwsAlarmActivationTime)
+		if activation == nil {
+			continue
+		}
+		records = append(records, alarmRecord{
+			description: a.IwsAlarmDescription,
+			activation:  *activation,
+			termination: times.parse(a.IwsAlarmTerminationTime),
+			active:      a.IwsAlarmTerminationTime == "",
+		})
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].activation.Before(records[j].activation) })
+
+	rate := alarmRateFrame(records, from, to, opts.operators)
+	rates := rate.Fields[1]
+	var total, peak float64
+	flooded := 0
+	for i := 0; i < rates.Len(); i++ {
+		r := rates.At(i).(float64)
+		total += r
+		peak = max(peak, r)
+		if r > opts.floodThreshold {
+			flooded++
+		}
+	}
+	var avg, floodPct float64
+	if n := rates.Len(); n > 0 {
+		avg = total / float64(n)
+		floodPct = 100 * float64(flooded) / float64(n)
+	}
+
+	chattering := chatteringFrame(records, opts.chatter)
+	standing, stale := standingFrame(records, now, opts.staleAfter)
+	standingRows, _ := standing.RowLen()
+	chatteringRows, _ := chattering.RowLen()
+
+	summary := data.NewFrame("Alarm KPIs",
+		data.NewField("Metric", nil, []string{
+			"Total alarms",
+			"Average alarm rate per operator per 10 min",
+			"Peak alarm rate per operator per 10 min",
+			"Time in flood (%)",
+			"Chattering alarms",
+			"Standing alarms",
+			"Stale alarms",
+		}),
+		data.NewField("Value", nil, []float64{
+			float64(len(records)),
+			avg,
+			peak,
+			floodPct,
+			float64(chatteringRows),
+			float64(standingRows),
+			float64(stale),
+		}),
+	)
+	summary.AppendNotices(times.notices()...)
+
+	return []*data.Frame{summary, rate, topAlarmsFrame(records), chattering, standing}
+}
+
+// alarmRateFrame counts the activations per operator in every window of
+// [from, to), stamped with the window start.
+func alarmRateFrame(records []alarmRecord, from, to time.Time, operators int) *data.Frame {
+	start := from.Truncate(kpiRateWindow)
+	var windows []time.Time
+	for t := start; t.Before(to); t = t.Add(kpiRateWindow) {
+		windows = append(windows, t)
+	}
+	counts := make([]float64, len(windows))
+	for _, r := range records {
+		if r.activation.Before(start) || !r.activation.Before(to) {
+			continue
+		}
+		counts[int(r.activation.Sub(start)/kpiRateWindow)]++
+	}
+	for i := range counts {
+		counts[i] /= float64(operators)
+	}
+
+	frame := data.NewFrame("Alarm rate",
+		data.NewField("time", nil, windows),
+		data.NewField("rate", nil, counts).SetConfig(&data.FieldConfig{DisplayNameFromDS: "Alarms per operator per 10 min"}),
+	)
+	frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti, TypeVersion: dataplaneVersion}
+	return frame
+}
+
+// topAlarmsFrame lists the most frequent alarms with their share of all
+// activations.
+func topAlarmsFrame(records []alarmRecord) *data.Frame {
+	counts := make(map[string]int64)
+	for _, r := range records {
+		counts[r.description]++
+	}
+	descriptions := byCount(counts)
+	if len(descriptions) > kpiTopAlarms {
+		descriptions = descriptions[:kpiTopAlarms]
+	}
+
+	frame := data.NewFrame("Top alarms",
+		data.NewField("Description", nil, []string{}),
+		data.NewField("Count", nil, []int64{}),
+		data.NewField("Share", nil, []float64{}).SetConfig(&data.FieldConfig{Unit: "percent"}),
+	)
+	for _, d := range descriptions {
+		frame.AppendRow(d, counts[d], 100*float64(counts[d])/float64(len(records)))
+	}
+	return frame
+}
+
+// byCount returns the keys of counts, most frequent first and then by name.
+func byCount(counts map[string]int64) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// chatteringFrame lists the alarms that re-activated within window of
+// their previous clearance, with how often they did.
+func chatteringFrame(records []alarmRecord, window time.Duration) *data.Frame {
+	last := make(map[string]alarmRecord)
+	chatter := make(map[string]int64)
+	for _, r := range records {
+		if prev, ok := last[r.description]; ok {
+			cleared := prev.activation
+			if prev.termination != nil {
+				cleared = *prev.termination
+			}
+			if r.activation.Sub(cleared) <= window {
+				chatter[r.description]++
+			}
+		}
+		last[r.description] = r
+	}
+
+	descriptions := byCount(chatter)
+
+	frame := data.NewFrame("Chattering alarms",
+		data.NewField("Description", nil, []string{}),
+		data.NewField("Re-activations", nil, []int64{}),
+	)
+	for _, d := range descriptions {
+		frame.AppendRow(d, chatter[d])
+	}
+	return frame
+}
+
+// standingFrame lists the alarms still active at now, longest standing
+// first, and counts the ones standing for longer than staleAfter.
+func standingFrame(records []alarmRecord, now time.Time, staleAfter time.Duration) (*data.Frame, int) {
+	frame := data.NewFrame("Standing alarms",
+		data.NewField("Description", nil, []string{}),
+		data.NewField("Activation Time", nil, []time.Time{}),
+		data.NewField("Duration", nil, []float64{}).SetConfig(&data.FieldConfig{Unit: "s"}),
+		data.NewField("Stale", nil, []bool{}),
+	)
+
+	stale := 0
+	// records are sorted by activation, so the longest standing come first.
+	for _, r := range records {
+		if !r.active {
+			continue
+		}
+		d := now.Sub(r.activation)
+		if d > staleAfter {
+			stale++
+		}
+		frame.AppendRow(r.description, r.activation, d.Seconds(), d > staleAfter)
+	}
+	return frame, stale
+}
@@ -1,79 +1,257 @@
 package plugin
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/init/in-view/pkg/inview"
+	"github.com/init/in-view/pkg/models"
+)
 
-type Variables struct {
-	ID           int    `json:"id"`
-	VariableName string `json:"variableName"`
+type LiveValueTimeseries struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	Quality   int       `json:"quality"`
+	// Null marks a sample without a usable value. It is sent as a null so
+	// that graphs show a gap.
+	Null bool `json:"-"`
 }
 
-type Connections struct {
-	ID             int    `json:"id"`
-	ConnectionName string `json:"name"`
-}
+type queryModel struct {
+	QueryText string  `json:"queryText"`
+	Constant  float64 `json:"constant"`
+
+	ConnectionId   *int   `json:"connectionId"`
+	ConnectionText string `json:"connectionText"`
+	VariableId     *int   `json:"variableId"`
+	VariableText   string `json:"variableText"`
+	IsLive         bool   `json:"isLive"`
+	IsAlarm        bool   `json:"isAlarm"`
+	IsEvent        bool   `json:"isEvent"`
+	// IsStatistics returns a table of summary statistics per variable over
+	// the query range instead of the samples.
+	IsStatistics bool `json:"isStatistics"`
+	// IsCurrent returns the latest value of each variable, looking back
+	// CurrentLookback (default "24h") from the end of the query range.
+	IsCurrent       bool   `json:"isCurrent"`
+	CurrentLookback string `json:"currentLookback"`
+	// IsAlarmKPI returns ISA-18.2 alarm performance metrics computed from
+	// every alarm page of the query range. Operators (default 1) divides
+	// the alarm rates, FloodThreshold (default 10) is the alarms per
+	// operator per 10 minutes that count as a flood, ChatterSeconds
+	// (default 60) is how soon a re-activation counts as chattering and
+	// StaleAfter (default "24h") is how long an alarm stands to be stale.
+	IsAlarmKPI     bool    `json:"isAlarmKpi"`
+	Operators      int     `json:"operators"`
+	FloodThreshold float64 `json:"floodThreshold"`
+	ChatterSeconds int     `json:"chatterSeconds"`
+	StaleAfter     string  `json:"staleAfter"`
+
+	Prefix  string `json:"prefix"`
+	OpcTags string `json:"opcTags"`
+
+	PageIndex int `json:"pageIndex"`
+	PageSize  int `json:"pageSize"`
+	// AllPages fetches every alarm or event page of the time range instead
+	// of the single page selected by PageIndex.
+	AllPages bool `json:"allPages"`
+	// ActiveOnly drops cleared alarms from alarm queries, leaving the
+	// standing alarms. Filtering happens after paging, so single pages may
+	// come back short.
+	ActiveOnly bool `json:"activeOnly"`
+	// MaxRows caps the rows returned in AllPages mode. Zero uses the
+	// datasource default.
+	MaxRows int `json:"maxRows"`
+
+	// Downsample selects how history series longer than MaxDataPoints are
+	// reduced: "lttb" (default), "minmax", "firstlast" or "none".
+	Downsample string `json:"downsample"`
+
+	// Aggregation reduces history series to one value per time bucket:
+	// avg, twavg (time-weighted avg), min, max, sum, count, first, last,
+	// range or stddev. Empty returns raw samples.
+	Aggregation string `json:"aggregation"`
+	// BucketSize is the aggregation bucket, e.g. "1h" or "1d". Empty uses
+	// Grafana's interval.
+	BucketSize string `json:"bucketSize"`
+
+	// Transform turns cumulative or rate signals into what is charted:
+	// "derivative", "rate" (non-negative, counter-aware), "integral" or
+	// "delta" (counter increase per bucket of BucketSize). TransformUnit is
+	// the time unit of derivatives, rates and integrals: "s", "m" or "h".
+	Transform     string `json:"transform"`
+	TransformUnit string `json:"transformUnit"`
+	// CounterMax is the value a counter rolls over at. Zero treats every
+	// drop as a reset to zero.
+	CounterMax float64 `json:"counterMax"`
 
-type LiveValues struct {
-	ID  int `json:"VariableId"`
-	Val any `json:"Value"`
+	// QualityMode selects what happens to samples whose quality is not
+	// good: "all" (default) keeps them, "exclude" drops them and "null"
+	// replaces their value with null.
+	QualityMode string `json:"qualityMode"`
+	// ShowQuality adds a Good/Uncertain/Bad quality field to history frames.
+	ShowQuality bool `json:"showQuality"`
+
+	// OutputFormat selects "series" (default), one frame per variable, or
+	// "wide", a single frame with a shared time field and a field per
+	// variable.
+	OutputFormat string `json:"outputFormat"`
+	// AlignTolerance merges timestamps this close together, e.g. "1s", into
+	// one row of a wide frame. Empty aligns exact timestamps only.
+	AlignTolerance string `json:"alignTolerance"`
+	// AlignFill fills the wide-frame cells a variable has no sample for:
+	// "null" (default), "previous" or "linear". Expressions default to
+	// "previous" instead.
+	AlignFill string `json:"alignFill"`
+
+	// GapThreshold marks the holes in history series longer than this,
+	// e.g. "5m", as gaps. GapFactor marks holes longer than this multiple
+	// of the logging interval instead when no threshold is set.
+	GapThreshold string  `json:"gapThreshold"`
+	GapFactor    float64 `json:"gapFactor"`
+	// GapFill fills the gaps: "null" (default), "previous", "linear",
+	// "zero" or "constant" with GapFillValue.
+	GapFill      string  `json:"gapFill"`
+	GapFillValue float64 `json:"gapFillValue"`
+
+	// Expression derives a series from the history of several variables,
+	// e.g. "$12 - $13" or "max(${Tank 1}, ${Tank 2}) * 24". Variables are
+	// referenced by ID, by an alias from Aliases or by name. The series are
+	// aligned like a wide frame before evaluation, holding each variable's
+	// last value unless AlignFill says otherwise, and the result is
+	// returned as its own frame named ExpressionName.
+	Expression     string         `json:"expression"`
+	ExpressionName string         `json:"expressionName"`
+	Aliases        map[string]int `json:"aliases"`
+
+	VariableIds   []int              `json:"variableIds"`
+	VariableNames []string           `json:"variableNames"`
+	Variables     []inview.Variables `json:"variables"`
 }
 
-type CustomTime struct {
-	time.Time
+// detectGaps reports whether the query asks for gap detection.
+func (qm queryModel) detectGaps() bool {
+	return qm.GapThreshold != "" || qm.GapFactor > 0
 }
 
-type rawLiveValue struct {
-	VariableId int     `json:"VariableId"`
-    Value      float64 `json:"Value"`
-    Timestamp  string  `json:"timestamp"`
-    Quality    int     `json:"quality"`
+// validate checks the query options that cannot be defaulted.
+func (qm queryModel) validate(query backend.DataQuery) error {
+	if !validDownsample(qm.Downsample) {
+		return fmt.Errorf("unknown downsample algorithm %q", qm.Downsample)
+	}
+	if !validQualityMode(qm.QualityMode) {
+		return fmt.Errorf("unknown quality mode %q", qm.QualityMode)
+	}
+	if !validAggregation(qm.Aggregation) {
+		return fmt.Errorf("unknown aggregation %q", qm.Aggregation)
+	}
+	if !validTransform(qm.Transform) {
+		return fmt.Errorf("unknown transform %q", qm.Transform)
+	}
+	if _, err := qm.transformUnit(); err != nil {
+		return err
+	}
+	if qm.Aggregation != "" || qm.Transform == transformDelta {
+		if _, err := qm.bucketSize(query); err != nil {
+			return err
+		}
+	}
+	if !validOutputFormat(qm.OutputFormat) {
+		return fmt.Errorf("unknown output format %q", qm.OutputFormat)
+	}
+	if !validAlignFill(qm.AlignFill) {
+		return fmt.Errorf("unknown align fill %q", qm.AlignFill)
+	}
+	if _, err := qm.alignTolerance(); err != nil {
+		return err
+	}
+	if !validGapFill(qm.GapFill) {
+		return fmt.Errorf("unknown gap fill %q", qm.GapFill)
+	}
+	if _, err := qm.gapThreshold(); err != nil {
+		return err
+	}
+	if qm.GapFactor < 0 {
+		return fmt.Errorf("invalid gap factor %v", qm.GapFactor)
+	}
+	// Only history queries evaluate the expression.
+	if qm.IsLive {
+		if _, err := qm.expression(); err != nil {
+			return err
+		}
+	}
+	if _, err := qm.currentLookback(); err != nil {
+		return err
+	}
+	if _, err := qm.alarmKPIOptions(); err != nil {
+		return err
+	}
+	return nil
 }
 
-type LiveValueTimeseries struct {
-	Timestamp time.Time `json:"timestamp"`
-	Value     float64   `json:"value"`
+// alignTolerance parses AlignTolerance, which defaults to zero.
+func (qm queryModel) alignTolerance() (time.Duration, error) {
+	if qm.AlignTolerance == "" {
+		return 0, nil
+	}
+	d, err := models.ParseDuration(qm.AlignTolerance)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid align tolerance %q", qm.AlignTolerance)
+	}
+	return d, nil
 }
 
-var errResp struct {
-	Errors  map[string][]string `json:"errors"`
-	Type    string              `json:"type"`
-	Title   string              `json:"title"`
-	Status  int                 `json:"status"`
-	TraceId string              `json:"traceId"`
+// variableIDs returns the IDs of the selected variables.
+func (qm queryModel) variableIDs() []int {
+	ids := make([]int, len(qm.Variables))
+	for i, v := range qm.Variables {
+		ids[i] = v.ID
+	}
+	return ids
 }
 
-type queryModel struct {
-	QueryText      string  `json:"queryText"`
-	Constant       float64 `json:"constant"`
-
-	ConnectionId   *int    `json:"connectionId"`
-	ConnectionText string  `json:"connectionText"`
-	VariableId     *int    `json:"variableId"`
-	VariableText   string  `json:"variableText"`
-	IsLive		   bool    `json:"isLive"`
-	IsAlarm        bool    `json:"isAlarm"`
-	IsEvent        bool    `json:"isEvent"`
-
- 	Prefix         string `json:"prefix"`
-    OpcTags         string `json:"opcTags"`
-
-	PageIndex      int     `json:"pageIndex"`
-	PageSize       int     `json:"pageSize"`
-
-	VariableIds     []int    `json:"variableIds"`
-	VariableNames   []string  `json:"variableNames"`
-	Variables [] Variables    `json:"variables"`
+// historyIDs returns the IDs of the variables whose history the query needs:
+// the selected variables plus the ones its expression references.
+func (qm queryModel) historyIDs() []int {
+	ids := qm.variableIDs()
+	e, _ := qm.expression()
+	if e == nil {
+		return ids
+	}
+	for _, id := range e.ids() {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
 
+// defaultPageSize is the page size when the query leaves it unset.
+const defaultPageSize = 10
 
-type AlarmLog struct {
-	IwsAlarmDescription      string `json:"iwsAlarmDescription"`
-	IwsAlarmActivationTime   string `json:"iwsAlarmActivationTime"`
-	IwsAlarmTerminationTime  string `json:"iwsAlarmTerminationTime"`
-}
+// allPagesPageSize is the smallest page size used when walking all pages,
+// chosen to keep the number of round-trips low.
+const allPagesPageSize = 500
 
-type EventLog struct {
-	IwsEventDescription      string `json:"iwsEventDescription"`
-	IwsEventTimestamp   string `json:"iwsEventTimestamp"`
+func (qm queryModel) pageIndex() int {
+	if qm.PageIndex < 0 {
+		return 0
+	}
+	return qm.PageIndex
 }
 
+// pageSize returns the rows per request. Walking all pages never uses pages
+// smaller than allPagesPageSize, since the editor always sends its own page
+// size and small pages would multiply the requests.
+func (qm queryModel) pageSize() int {
+	switch {
+	case qm.AllPages:
+		return max(qm.PageSize, allPagesPageSize)
+	case qm.PageSize > 0:
+		return qm.PageSize
+	default:
+		return defaultPageSize
+	}
+}
@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// pageFunc fetches a single page of rows.
+type pageFunc[T any] func(ctx context.Context, pageIndex, pageSize int) ([]T, error)
+
+// fetchRows returns the page selected in the query, or every page when the
+// query asks for all of them. It reports whether maxRows truncated the result.
+func fetchRows[T comparable](ctx context.Context, qm queryModel, maxRows int, fetch pageFunc[T]) ([]T, bool, error) {
+	if !qm.AllPages {
+		rows, err := fetch(ctx, qm.pageIndex(), qm.pageSize())
+		return rows, false, err
+	}
+	return fetchAllPages(ctx, qm.pageSize(), maxRows, fetch)
+}
+
+// fetchAllPages walks pages from the first one until InView returns a short
+// page or maxRows rows have been collected. When new rows arrive while
+// paging, the head of a page repeats the tail of the page before; those rows
+// are dropped. Repeated rows anywhere else are kept, since InView rows need
+// not be unique.
+func fetchAllPages[T comparable](ctx context.Context, pageSize, maxRows int, fetch pageFunc[T]) ([]T, bool, error) {
+	var rows, prev []T
+
+	for pageIndex := 0; ; pageIndex++ {
+		page, err := fetch(ctx, pageIndex, pageSize)
+		if err != nil {
+			return nil, false, err
+		}
+
+		fresh := page[boundaryOverlap(prev, page):]
+		for _, row := range fresh {
+			if maxRows > 0 && len(rows) >= maxRows {
+				return rows, true, nil
+			}
+			rows = append(rows, row)
+		}
+
+		// A short page is the last one. A page that only repeats the one
+		// before means InView is not advancing, so stop instead of looping.
+		if len(page) < pageSize || len(fresh) == 0 {
+			return rows, false, nil
+		}
+		prev = page
+	}
+}
+
+// boundaryOverlap returns the length of the longest tail of prev that page
+// starts with.
+func boundaryOverlap[T comparable](prev, page []T) int {
+	for n := min(len(prev), len(page)); n > 0; n-- {
+		if slices.Equal(prev[len(prev)-n:], page[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// truncatedNotice tells the user that the row cap cut the result short.
+func truncatedNotice(maxRows int) data.Notice {
+	return data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("Result truncated to %d rows. Narrow the time range or raise the row limit.", maxRows),
+	}
+}
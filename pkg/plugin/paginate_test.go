@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// pagesOf serves rows in pages, like InView, and counts the requests.
+func pagesOf(rows []int, requests *int) pageFunc[int] {
+	return func(_ context.Context, pageIndex, pageSize int) ([]int, error) {
+		*requests++
+		start := min(pageIndex*pageSize, len(rows))
+		return rows[start:min(start+pageSize, len(rows))], nil
+	}
+}
+
+func TestFetchAllPages(t *testing.T) {
+	t.Run("short page ends", func(t *testing.T) {
+		var requests int
+		rows, truncated, err := fetchAllPages(context.Background(), 3, 0, pagesOf([]int{1, 2, 3, 4, 5, 6, 7}, &requests))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(rows, []int{1, 2, 3, 4, 5, 6, 7}) || truncated || requests != 3 {
+			t.Errorf("rows %v, truncated %v after %d requests", rows, truncated, requests)
+		}
+	})
+
+	t.Run("row cap truncates", func(t *testing.T) {
+		var requests int
+		rows, truncated, err := fetchAllPages(context.Background(), 3, 5, pagesOf([]int{1, 2, 3, 4, 5, 6, 7}, &requests))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(rows, []int{1, 2, 3, 4, 5}) || !truncated {
+			t.Errorf("rows %v, truncated %v", rows, truncated)
+		}
+	})
+
+	t.Run("row cap on a page boundary", func(t *testing.T) {
+		var requests int
+		rows, truncated, err := fetchAllPages(context.Background(), 3, 6, pagesOf([]int{1, 2, 3, 4, 5, 6}, &requests))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(rows, []int{1, 2, 3, 4, 5, 6}) || truncated {
+			t.Errorf("rows %v, truncated %v", rows, truncated)
+		}
+	})
+
+	t.Run("rows repeated across a boundary are dropped", func(t *testing.T) {
+		// A new row arriving at the head shifts the second page by one.
+		pages := [][]int{{1, 2, 3}, {3, 4, 5}, {6}}
+		fetch := func(_ context.Context, pageIndex, _ int) ([]int, error) {
+			return pages[pageIndex], nil
+		}
+		rows, truncated, err := fetchAllPages(context.Background(), 3, 0, fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(rows, []int{1, 2, 3, 4, 5, 6}) || truncated {
+			t.Errorf("rows %v, truncated %v", rows, truncated)
+		}
+	})
+
+	t.Run("repeated rows away from a boundary are kept", func(t *testing.T) {
+		// Identical rows, like two equal event log entries, are real rows.
+		pages := [][]int{{1, 1, 2}, {3, 1, 1}, {4}}
+		fetch := func(_ context.Context, pageIndex, _ int) ([]int, error) {
+			return pages[pageIndex], nil
+		}
+		rows, _, err := fetchAllPages(context.Background(), 3, 0, fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(rows, []int{1, 1, 2, 3, 1, 1, 4}) {
+			t.Errorf("rows %v", rows)
+		}
+	})
+
+	t.Run("no progress stops", func(t *testing.T) {
+		// A server that ignores the page index returns the first page forever.
+		var requests int
+		fetch := func(_ context.Context, _, _ int) ([]int, error) {
+			requests++
+			return []int{1, 2, 3}, nil
+		}
+		rows, truncated, err := fetchAllPages(context.Background(), 3, 0, fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(rows, []int{1, 2, 3}) || truncated || requests != 2 {
+			t.Errorf("rows %v, truncated %v after %d requests", rows, truncated, requests)
+		}
+	})
+}
+
+func TestAllPagesPageSize(t *testing.T) {
+	for _, tc := range []struct {
+		qm   queryModel
+		want int
+	}{
+		{queryModel{PageSize: 20}, 20},
+		{queryModel{}, defaultPageSize},
+		{queryModel{AllPages: true}, allPagesPageSize},
+		{queryModel{AllPages: true, PageSize: 20}, allPagesPageSize},
+		{queryModel{AllPages: true, PageSize: 1000}, 1000},
+	} {
+		if got := tc.qm.pageSize(); got != tc.want {
+			t.Errorf("%+v: page size %d, want %d", tc.qm, got, tc.want)
+		}
+	}
+}
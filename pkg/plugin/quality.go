@@ -0,0 +1,65 @@
+package plugin
+
+// Quality handling modes selectable per query.
+const (
+	qualityModeAll     = "all"
+	qualityModeExclude = "exclude"
+	qualityModeNull    = "null"
+)
+
+// Quality texts. InView reports OPC DA quality codes, whose two high bits of
+// the low byte carry the quality class.
+const (
+	qualityGood      = "Good"
+	qualityUncertain = "Uncertain"
+	qualityBad       = "Bad"
+)
+
+// qualityCodeGood is the OPC DA quality code of a good sample.
+const qualityCodeGood = 0xC0
+
+func validQualityMode(mode string) bool {
+	switch mode {
+	case "", qualityModeAll, qualityModeExclude, qualityModeNull:
+		return true
+	default:
+		return false
+	}
+}
+
+// qualityText maps an OPC DA quality code to Good, Uncertain or Bad.
+func qualityText(q int) string {
+	switch q & 0xC0 {
+	case qualityCodeGood:
+		return qualityGood
+	case 0x40:
+		return qualityUncertain
+	default:
+		return qualityBad
+	}
+}
+
+func isGoodQuality(q int) bool {
+	return qualityText(q) == qualityGood
+}
+
+// applyQuality drops the non-good samples of s, or turns them into nulls, as
+// selected by mode.
+func (s *series) applyQuality(mode string) {
+	switch mode {
+	case qualityModeExclude:
+		kept := s.points[:0]
+		for _, p := range s.points {
+			if isGoodQuality(p.Quality) {
+				kept = append(kept, p)
+			}
+		}
+		s.points = kept
+	case qualityModeNull:
+		for i := range s.points {
+			if !isGoodQuality(s.points[i].Quality) {
+				s.points[i].Null = true
+			}
+		}
+	}
+}
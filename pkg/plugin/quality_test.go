@@ -0,0 +1,29 @@
+package plugin
+
+import "testing"
+
+func TestApplyQuality(t *testing.T) {
+	points := func() []LiveValueTimeseries {
+		p := testPoints(3, func(i int) float64 { return float64(i) })
+		p[0].Quality = 192 // good
+		p[1].Quality = 64  // uncertain
+		p[2].Quality = 0   // bad
+		return p
+	}
+
+	s := &series{points: points()}
+	s.applyQuality(qualityModeExclude)
+	if len(s.points) != 1 || s.points[0].Value != 0 {
+		t.Fatalf("exclude: got %+v", s.points)
+	}
+
+	s = &series{points: points()}
+	s.applyQuality(qualityModeNull)
+	frame := s.frame(true)
+	if v, ok := frame.Fields[1].ConcreteAt(1); ok {
+		t.Errorf("null: uncertain sample should be null, got %v", v)
+	}
+	if q := frame.Fields[2].At(2); q != qualityBad {
+		t.Errorf("quality = %v, want %s", q, qualityBad)
+	}
+}
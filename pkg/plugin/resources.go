@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/init/in-view/pkg/inview"
+)
+
+func (d *Datasource) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return sendError(sender, http.StatusBadRequest, fmt.Sprintf("Invalid URL: %v", err))
+	}
+	values := u.Query()
+
+	switch req.Path {
+	case "Variables":
+		vars, err := d.client.ListVariables(ctx, inview.VariablesOptions{
+			Page:            intParam(values, "page", 0),
+			ItemsPerPage:    intParam(values, "itemsPerPage", 20),
+			SkipFilterConns: boolParam(values, "skipFilterConns", false),
+			ConnectionID:    intParam(values, "connId", 0),
+			LikeParam:       values.Get("likeParam"),
+			SkipPagination:  boolParam(values, "skipPagination", true),
+		})
+		if err != nil {
+			return sendClientError(ctx, sender, err)
+		}
+		return sendJSON(sender, vars)
+
+	case "Connections":
+		conns, err := d.client.ListConnections(ctx, inview.ConnectionsOptions{
+			PageIndex:            intParam(values, "pageIndex", 0),
+			PageSize:             intParam(values, "pageSize", 10),
+			SkipConnectionFilter: boolParam(values, "skipConnectionFilter", false),
+			SearchText:           values.Get("searchText"),
+		})
+		if err != nil {
+			return sendClientError(ctx, sender, err)
+		}
+		conns = append(conns, inview.Connections{
+			ID:             0,
+			ConnectionName: "Internal",
+		})
+		sort.Slice(conns, func(i, j int) bool {
+			return conns[i].ID < conns[j].ID
+		})
+		return sendJSON(sender, conns)
+	}
+
+	return sendError(sender, http.StatusNotFound, fmt.Sprintf("Unknown path: %s", req.Path))
+}
+
+func intParam(values url.Values, key string, def int) int {
+	n, err := strconv.Atoi(values.Get(key))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func boolParam(values url.Values, key string, def bool) bool {
+	b, err := strconv.ParseBool(values.Get(key))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+func sendJSON(sender backend.CallResourceResponseSender, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return sendError(sender, http.StatusInternalServerError, fmt.Sprintf("Error marshaling response: %v", err))
+	}
+
+	return sender.Send(&backend.CallResourceResponse{
+		Status:  http.StatusOK,
+		Headers: map[string][]string{"Content-Type": {"application/json"}},
+		Body:    body,
+	})
+}
+
+func sendError(sender backend.CallResourceResponseSender, status int, message string) error {
+	return sender.Send(&backend.CallResourceResponse{
+		Status: status,
+		Body:   []byte(message),
+	})
+}
+
+// sendClientError reports an InView client error to the resource caller.
+func sendClientError(ctx context.Context, sender backend.CallResourceResponseSender, err error) error {
+	if msg, ok := contextError(err); ok {
+		if ctx.Err() != nil {
+			// The caller is gone, there is nobody left to answer.
+			return ctx.Err()
+		}
+		return sendError(sender, http.StatusGatewayTimeout, msg)
+	}
+
+	log.DefaultLogger.Error("PLUGIN RESOURCE -- InView request failed", "error", err)
+
+	var apiErr *inview.APIError
+	if errors.As(err, &apiErr) {
+		return sendError(sender, resourceStatus(apiErr.StatusCode), apiErr.Error())
+	}
+	return sendError(sender, http.StatusBadGateway, err.Error())
+}
+
+// resourceStatus maps an InView HTTP status onto the status returned to the
+// resource caller. InView rejecting the plugin's credentials is a gateway
+// failure: passing 401 or 403 through would read as the Grafana user lacking
+// access and can log them out of the frontend.
+func resourceStatus(code int) int {
+	switch code {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return http.StatusBadGateway
+	default:
+		return code
+	}
+}
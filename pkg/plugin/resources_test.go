@@ -0,0 +1,31 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/init/in-view/pkg/inview"
+)
+
+func TestSendClientErrorUpstreamAuth(t *testing.T) {
+	for _, code := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		var resp *backend.CallResourceResponse
+		sender := backend.CallResourceResponseSenderFunc(func(r *backend.CallResourceResponse) error {
+			resp = r
+			return nil
+		})
+		err := sendClientError(context.Background(), sender, &inview.APIError{StatusCode: code, Title: "Invalid API key"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != http.StatusBadGateway {
+			t.Errorf("upstream %d: got status %d, want %d", code, resp.Status, http.StatusBadGateway)
+		}
+		if !strings.Contains(string(resp.Body), "Invalid API key") {
+			t.Errorf("upstream %d: body %s lost the detail", code, resp.Body)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+)
+
+// series is the history of a single variable on its way to becoming a frame.
+type series struct {
+	id      int
+	name    string
+	labels  data.Labels
+	config  *data.FieldConfig
+	points  []LiveValueTimeseries
+	notices []data.Notice
+}
+
+// groupSeries groups the history samples by variable, reading timestamps as
+// wall-clock times in loc. Samples without a readable timestamp are dropped
+// and reported in a notice on their series. Every series is sorted by time
+// and the series are returned sorted by variable name.
+func groupSeries(ctx context.Context, raw []inview.RawLiveValue, variables []inview.Variables, loc *time.Location) ([]*series, error) {
+	grouped := make(map[int][]LiveValueTimeseries)
+	parsers := make(map[int]*timestampParser)
+	for _, r := range raw {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		parser, ok := parsers[r.VariableId]
+		if !ok {
+			parser = &timestampParser{loc: loc}
+			parsers[r.VariableId] = parser
+		}
+		t := parser.parse(r.Timestamp)
+		if t == nil {
+			continue
+		}
+		grouped[r.VariableId] = append(grouped[r.VariableId], LiveValueTimeseries{
+			Timestamp: *t,
+			Value:     r.Value,
+			Quality:   r.Quality,
+		})
+	}
+
+	varNameMap := make(map[int]string, len(variables))
+	for _, v := range variables {
+		varNameMap[v.ID] = v.VariableName
+	}
+
+	out := make([]*series, 0, len(grouped))
+	for varId, parser := range parsers {
+		points := grouped[varId]
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].Timestamp.Before(points[j].Timestamp)
+		})
+
+		name := varNameMap[varId]
+		if name == "" {
+			name = strconv.Itoa(varId)
+		}
+		out = append(out, &series{id: varId, name: name, points: points, notices: parser.notices()})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return strings.ToLower(out[i].name) < strings.ToLower(out[j].name)
+	})
+	return out, nil
+}
+
+// dataplaneVersion is the version of the dataplane frame types produced.
+var dataplaneVersion = data.FrameTypeVersion{0, 1}
+
+// frame builds the timeseries-multi frame of s, optionally with a quality
+// field. The value field carries the labels of s.
+func (s *series) frame(withQuality bool) *data.Frame {
+	times := make([]time.Time, len(s.points))
+	vals := make([]*float64, len(s.points))
+
+	for i, v := range s.points {
+		times[i] = v.Timestamp
+		if !v.Null {
+			val := v.Value
+			vals[i] = &val
+		}
+	}
+
+	frame := data.NewFrame(s.name,
+		data.NewField("time", nil, times),
+		data.NewField("value", s.labels, vals).SetConfig(s.config),
+	)
+	frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti, TypeVersion: dataplaneVersion}
+	if withQuality {
+		quality := make([]string, len(s.points))
+		for i, v := range s.points {
+			quality[i] = qualityText(v.Quality)
+		}
+		frame.Fields = append(frame.Fields, data.NewField("quality", s.labels, quality))
+	}
+	if len(s.notices) > 0 {
+		frame.AppendNotices(s.notices...)
+	}
+	return frame
+}
@@ -0,0 +1,13 @@
+package plugin
+
+import "time"
+
+// testPoints returns n samples one second apart with values from fn.
+func testPoints(n int, fn func(i int) float64) []LiveValueTimeseries {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]LiveValueTimeseries, n)
+	for i := range points {
+		points[i] = LiveValueTimeseries{Timestamp: t0.Add(time.Duration(i) * time.Second), Value: fn(i)}
+	}
+	return points
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/init/in-view/pkg/inview"
+)
+
+// statisticsFrame reduces the history of every selected variable to one
+// table row of summary statistics, in the order of the Variables list.
+// Variables without usable samples get a zero count and null statistics.
+func (d *Datasource) statisticsFrame(ctx context.Context, qm queryModel, raw []inview.RawLiveValue) (*data.Frame, error) {
+	list, err := groupSeries(ctx, raw, qm.Variables, d.settings.Location)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*series, len(list))
+	for _, s := range list {
+		byID[s.id] = s
+	}
+
+	frame := data.NewFrame("Statistics",
+		data.NewField("Variable", nil, []string{}),
+		data.NewField("Variable ID", nil, []int64{}),
+		data.NewField("Min", nil, []*float64{}),
+		data.NewField("Max", nil, []*float64{}),
+		data.NewField("Avg", nil, []*float64{}),
+		data.NewField("StdDev", nil, []*float64{}),
+		data.NewField("Last", nil, []*float64{}),
+		data.NewField("Last Time", nil, []*time.Time{}),
+		data.NewField("Count", nil, []int64{}),
+	)
+
+	for _, v := range qm.Variables {
+		s, ok := byID[v.ID]
+		if !ok {
+			s = &series{id: v.ID, name: v.VariableName}
+		}
+		s.applyQuality(qm.QualityMode)
+
+		var valid []LiveValueTimeseries
+		for _, p := range s.points {
+			if !p.Null {
+				valid = append(valid, p)
+			}
+		}
+
+		stat := func(fn string) *float64 {
+			if len(valid) == 0 {
+				return nil
+			}
+			v := reduce(fn, valid)
+			return &v
+		}
+		var lastTime *time.Time
+		if len(valid) > 0 {
+			lastTime = &valid[len(valid)-1].Timestamp
+		}
+
+		frame.AppendRow(s.name, int64(v.ID),
+			stat(aggMin), stat(aggMax), stat(aggAvg), stat(aggStdDev), stat(aggLast),
+			lastTime, int64(len(valid)))
+		frame.AppendNotices(s.notices...)
+	}
+	return frame, nil
+}
@@ -0,0 +1,36 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/init/in-view/pkg/inview"
+	"github.com/init/in-view/pkg/models"
+)
+
+func TestStatisticsFrame(t *testing.T) {
+	ds := &Datasource{settings: &models.PluginSettings{Location: time.UTC}}
+	qm := queryModel{Variables: []inview.Variables{{ID: 2, VariableName: "Level"}, {ID: 1, VariableName: "Empty"}}}
+	raw := []inview.RawLiveValue{
+		{VariableId: 2, Value: 3, Timestamp: "2024-01-01T00:00:00"},
+		{VariableId: 2, Value: 1, Timestamp: "2024-01-01T00:00:01"},
+	}
+
+	frame, err := ds.statisticsFrame(context.Background(), qm, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := frame.RowLen(); rows != 2 {
+		t.Fatalf("got %d rows, want 2", rows)
+	}
+	if name := frame.Fields[0].At(0); name != "Level" {
+		t.Errorf("first row = %v, want Level", name)
+	}
+	if last, _ := frame.Fields[6].ConcreteAt(0); last != 1.0 {
+		t.Errorf("last = %v, want 1", last)
+	}
+	if avg, ok := frame.Fields[4].ConcreteAt(1); ok {
+		t.Errorf("avg of an empty variable = %v, want null", avg)
+	}
+}
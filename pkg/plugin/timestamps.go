@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// zonedLayouts are the timestamp layouts InView emits with an offset.
+var zonedLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+}
+
+// localLayouts are the timestamp layouts InView emits without an offset,
+// which are wall-clock times in the server's timezone. Fractional seconds
+// are optional in all of them.
+var localLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseTimestamp parses an InView timestamp. Timestamps without an offset
+// are read as wall-clock times in loc.
+func parseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range zonedLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", s)
+}
+
+// timestampParser parses the timestamps of one frame and counts the ones
+// it cannot read, so that they can be reported once instead of per row.
+type timestampParser struct {
+	loc     *time.Location
+	failed  int
+	example string
+}
+
+// parse returns the timestamp s, or nil when s is empty or cannot be parsed.
+func (p *timestampParser) parse(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := parseTimestamp(s, p.loc)
+	if err != nil {
+		if p.failed == 0 {
+			p.example = s
+		}
+		p.failed++
+		return nil
+	}
+	return &t
+}
+
+// notices returns a warning about the unparseable timestamps, if any.
+func (p *timestampParser) notices() []data.Notice {
+	if p.failed == 0 {
+		return nil
+	}
+	return []data.Notice{{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("%d row(s) had unparseable timestamps, e.g. %q", p.failed, p.example),
+	}}
+}
@@ -0,0 +1,37 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	loc := time.FixedZone("plant", 2*60*60)
+	want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, s := range []string{
+		"2024-03-01T12:00:00",
+		"2024-03-01T12:00:00.000000",
+		"2024-03-01 12:00:00.0",
+		"2024-03-01T10:00:00Z",
+		"2024-03-01T11:00:00+01:00",
+		"2024-03-01T12:00",
+	} {
+		got, err := parseTimestamp(s, loc)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("%q = %v, want %v", s, got.UTC(), want)
+		}
+	}
+
+	p := timestampParser{loc: loc}
+	if p.parse("yesterday") != nil || p.parse("") != nil {
+		t.Error("expected nil for unparseable and empty timestamps")
+	}
+	if n := p.notices(); len(n) != 1 || p.failed != 1 {
+		t.Errorf("got %d failures and notices %v, want 1", p.failed, n)
+	}
+}
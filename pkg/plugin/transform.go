@@ -0,0 +1,160 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Transforms of cumulative and rate signals selectable per query.
+const (
+	transformDerivative = "derivative"
+	transformRate       = "rate"
+	transformIntegral   = "integral"
+	transformDelta      = "delta"
+)
+
+func validTransform(fn string) bool {
+	switch fn {
+	case "", transformDerivative, transformRate, transformIntegral, transformDelta:
+		return true
+	default:
+		return false
+	}
+}
+
+// transformUnit returns the time unit of derivatives, rates and integrals:
+// "s" (default), "m" or "h".
+func (qm queryModel) transformUnit() (time.Duration, error) {
+	switch qm.TransformUnit {
+	case "", "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unknown transform unit %q", qm.TransformUnit)
+	}
+}
+
+// changesUnit reports whether the query's transform or aggregation leaves
+// values in a different unit than the variable's.
+func (qm queryModel) changesUnit() bool {
+	switch qm.Transform {
+	case transformDerivative, transformRate, transformIntegral:
+		return true
+	}
+	return qm.Aggregation == aggCount
+}
+
+// changesRange reports whether the query's transform or aggregation can
+// leave values outside the variable's range, while in its unit.
+func (qm queryModel) changesRange() bool {
+	if qm.Transform == transformDelta {
+		return true
+	}
+	switch qm.Aggregation {
+	case aggSum, aggRange, aggStdDev:
+		return true
+	}
+	return false
+}
+
+// counterIncrease returns how much a counter grew from prev to cur. A drop
+// is a rollover past counterMax when that is set, and otherwise a reset to
+// zero, after which the counter counted up to cur.
+func counterIncrease(prev, cur, counterMax float64) (increase float64, reset bool) {
+	switch {
+	case cur >= prev:
+		return cur - prev, false
+	case counterMax > 0 && prev <= counterMax:
+		return counterMax - prev + cur, true
+	default:
+		return cur, true
+	}
+}
+
+// transform replaces the points of s with fn applied to them:
+//
+//   - derivative: change per unit of time between consecutive samples
+//   - rate: like derivative, but treating s as a counter, so that resets and
+//     rollovers never yield negative values
+//   - integral: running total of the area under s, with time in units
+//   - delta: counter increase per time bucket of the given size, aligned in
+//     loc and stamped with the bucket start
+//
+// Null samples stay null and are skipped when pairing consecutive samples.
+func (s *series) transform(fn string, unit, bucket time.Duration, loc *time.Location, counterMax float64) {
+	if fn == "" || len(s.points) == 0 {
+		return
+	}
+
+	var (
+		out    []LiveValueTimeseries
+		resets int
+		total  float64
+		prev   *LiveValueTimeseries
+	)
+	for i := range s.points {
+		p := s.points[i]
+		if p.Null {
+			if fn != transformDelta {
+				out = append(out, p)
+			}
+			continue
+		}
+
+		switch fn {
+		case transformIntegral:
+			if prev != nil {
+				dt := float64(p.Timestamp.Sub(prev.Timestamp)) / float64(unit)
+				total += (prev.Value + p.Value) / 2 * dt
+			}
+			out = append(out, LiveValueTimeseries{Timestamp: p.Timestamp, Value: total, Quality: p.Quality})
+
+		case transformDelta:
+			start := bucketStart(p.Timestamp, bucket, loc)
+			if n := len(out); n == 0 || !out[n-1].Timestamp.Equal(start) {
+				out = append(out, LiveValueTimeseries{Timestamp: start, Quality: p.Quality})
+			}
+			last := &out[len(out)-1]
+			last.Quality = min(last.Quality, p.Quality)
+			if prev != nil {
+				increase, reset := counterIncrease(prev.Value, p.Value, counterMax)
+				if reset {
+					resets++
+				}
+				last.Value += increase
+			}
+
+		default:
+			if prev != nil {
+				if dt := p.Timestamp.Sub(prev.Timestamp); dt > 0 {
+					change := p.Value - prev.Value
+					if fn == transformRate {
+						var reset bool
+						if change, reset = counterIncrease(prev.Value, p.Value, counterMax); reset {
+							resets++
+						}
+					}
+					out = append(out, LiveValueTimeseries{
+						Timestamp: p.Timestamp,
+						Value:     change / (float64(dt) / float64(unit)),
+						Quality:   min(prev.Quality, p.Quality),
+					})
+				}
+			}
+		}
+		prev = &s.points[i]
+	}
+	s.points = out
+
+	if resets > 0 {
+		s.notices = append(s.notices, data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     fmt.Sprintf("Detected %d counter reset(s) or rollover(s)", resets),
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransform(t *testing.T) {
+	// A counter sampled every second that resets to zero after 30.
+	counter := func() []LiveValueTimeseries {
+		return testPoints(6, func(i int) float64 { return []float64{10, 20, 30, 5, 15, 25}[i] })
+	}
+
+	s := &series{points: counter()}
+	s.transform(transformRate, time.Second, 0, time.UTC, 0)
+	for _, p := range s.points {
+		if p.Value < 0 {
+			t.Fatalf("rate: negative value %v", p.Value)
+		}
+	}
+	if len(s.points) != 5 || s.points[2].Value != 5 || len(s.notices) != 1 {
+		t.Errorf("rate: got %+v", s.points)
+	}
+
+	s = &series{points: counter()}
+	s.transform(transformDelta, time.Second, 3*time.Second, time.UTC, 0)
+	if len(s.points) != 2 || s.points[0].Value != 20 || s.points[1].Value != 25 {
+		t.Errorf("delta: got %+v", s.points)
+	}
+
+	s = &series{points: counter()}
+	s.transform(transformDerivative, time.Minute, 0, time.UTC, 0)
+	if s.points[0].Value != 600 || s.points[2].Value != -1500 {
+		t.Errorf("derivative: got %+v", s.points)
+	}
+
+	// A constant rate of 2/s integrates to 2 per second elapsed.
+	s = &series{points: testPoints(4, func(int) float64 { return 2 })}
+	s.transform(transformIntegral, time.Second, 0, time.UTC, 0)
+	if s.points[3].Value != 6 {
+		t.Errorf("integral: got %+v", s.points)
+	}
+}
+
+func TestDeltaAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Vienna")
+	if err != nil {
+		t.Skip(err)
+	}
+	// A counter counting one per hour across the switch to summer time.
+	var points []LiveValueTimeseries
+	for i := range 48 {
+		at := time.Date(2024, 3, 30, 12, 0, 0, 0, loc).Add(time.Duration(i) * time.Hour)
+		points = append(points, LiveValueTimeseries{Timestamp: at, Value: float64(i)})
+	}
+
+	s := &series{points: points}
+	s.transform(transformDelta, time.Second, 24*time.Hour, loc, 0)
+	for i, p := range s.points {
+		if p.Timestamp.In(loc).Hour() != 0 {
+			t.Errorf("bucket %d starts at %s, want local midnight", i, p.Timestamp.In(loc))
+		}
+		if i > 0 && !p.Timestamp.After(s.points[i-1].Timestamp) {
+			t.Errorf("bucket %d at %s does not follow %s", i, p.Timestamp, s.points[i-1].Timestamp)
+		}
+	}
+	if len(s.points) != 3 || s.points[1].Value != 23 {
+		t.Errorf("got %+v, want 23 on the short day", s.points)
+	}
+}
@@ -0,0 +1,208 @@
+package plugin
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Output formats of history queries.
+const (
+	outputSeries = "series"
+	outputWide   = "wide"
+)
+
+// Fill strategies for wide-frame cells a variable has no sample for.
+const (
+	alignFillNull     = "null"
+	alignFillPrevious = "previous"
+	alignFillLinear   = "linear"
+)
+
+func validOutputFormat(format string) bool {
+	switch format {
+	case "", outputSeries, outputWide:
+		return true
+	default:
+		return false
+	}
+}
+
+func validAlignFill(fill string) bool {
+	switch fill {
+	case "", alignFillNull, alignFillPrevious, alignFillLinear:
+		return true
+	default:
+		return false
+	}
+}
+
+// wideCell is one variable's value in a wide-frame row.
+type wideCell struct {
+	set     bool
+	null    bool
+	value   float64
+	quality int
+}
+
+// alignTimestamps returns the row times of a wide frame. Timestamps within
+// tolerance of the first timestamp of a row are merged into that row.
+func alignTimestamps(list []*series, tolerance time.Duration) []time.Time {
+	var all []time.Time
+	for _, s := range list {
+		for _, p := range s.points {
+			all = append(all, p.Timestamp)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Before(all[j]) })
+
+	var rows []time.Time
+	for _, t := range all {
+		if n := len(rows); n > 0 && !t.After(rows[n-1].Add(tolerance)) {
+			continue
+		}
+		rows = append(rows, t)
+	}
+	return rows
+}
+
+// alignSeries matches the samples of every series to shared rows. Samples
+// are matched to rows within tolerance; when a series has several samples in
+// a row the last one wins. Rows a series has no sample for are filled as
+// selected by fill. Explicit null samples stay null. The cells are returned
+// per series, in the order of list.
+func alignSeries(list []*series, tolerance time.Duration, fill string) ([]time.Time, [][]wideCell) {
+	rows := alignTimestamps(list, tolerance)
+
+	columns := make([][]wideCell, len(list))
+	for k, s := range list {
+		cells := make([]wideCell, len(rows))
+		for _, p := range s.points {
+			// Last row starting at or before the sample.
+			i := sort.Search(len(rows), func(i int) bool { return rows[i].After(p.Timestamp) }) - 1
+			cells[i] = wideCell{set: true, null: p.Null, value: p.Value, quality: p.Quality}
+		}
+		fillCells(cells, rows, fill)
+		columns[k] = cells
+	}
+	return rows, columns
+}
+
+// wideFrame joins the series into one timeseries-wide frame with a shared
+// time field and a value field per series, told apart by their labels. Rows
+// are aligned as described for alignSeries and then downsampled to
+// maxPoints rows as described for downsampleRows.
+func wideFrame(list []*series, tolerance time.Duration, fill string, withQuality bool, algo string, maxPoints int) *data.Frame {
+	rows, columns := alignSeries(list, tolerance, fill)
+	rows, columns, notices := downsampleRows(rows, columns, algo, maxPoints)
+
+	frame := data.NewFrame("", data.NewField("time", nil, rows))
+	frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesWide, TypeVersion: dataplaneVersion}
+	frame.AppendNotices(notices...)
+	for k, s := range list {
+		cells := columns[k]
+
+		vals := make([]*float64, len(rows))
+		for i, c := range cells {
+			if c.set && !c.null {
+				v := c.value
+				vals[i] = &v
+			}
+		}
+		frame.Fields = append(frame.Fields, data.NewField("value", s.labels, vals).SetConfig(s.config))
+
+		if withQuality {
+			quality := make([]*string, len(rows))
+			for i, c := range cells {
+				if c.set {
+					q := qualityText(c.quality)
+					quality[i] = &q
+				}
+			}
+			frame.Fields = append(frame.Fields, data.NewField("quality", s.labels, quality))
+		}
+
+		if len(s.notices) > 0 {
+			frame.AppendNotices(s.notices...)
+		}
+	}
+	return frame
+}
+
+// downsampleRows caps the aligned rows at about maxPoints using algo. Each
+// column is downsampled on its own share of the budget and the union of the
+// rows picked for any column is kept, so every column keeps its shape and
+// still has a value in every row. Downsampling the series before aligning
+// them would pick different times per series and leave a sparse frame.
+func downsampleRows(rows []time.Time, columns [][]wideCell, algo string, maxPoints int) ([]time.Time, [][]wideCell, []data.Notice) {
+	if algo == downsampleNone || maxPoints <= 0 || len(rows) <= maxPoints || len(columns) == 0 {
+		return rows, columns, nil
+	}
+
+	budget := max(maxPoints/len(columns), 3)
+	keep := make([]bool, len(rows))
+	for _, cells := range columns {
+		var points []LiveValueTimeseries
+		for i, c := range cells {
+			if c.set {
+				points = append(points, LiveValueTimeseries{Timestamp: rows[i], Value: c.value, Quality: c.quality, Null: c.null})
+			}
+		}
+		if len(points) > budget {
+			points, algo = downsamplePoints(algo, points, budget)
+		}
+		for _, p := range points {
+			keep[sort.Search(len(rows), func(i int) bool { return !rows[i].Before(p.Timestamp) })] = true
+		}
+	}
+
+	var kept []time.Time
+	out := make([][]wideCell, len(columns))
+	for i, t := range rows {
+		if !keep[i] {
+			continue
+		}
+		kept = append(kept, t)
+		for k := range columns {
+			out[k] = append(out[k], columns[k][i])
+		}
+	}
+	if algo == "" {
+		algo = downsampleLTTB
+	}
+	return kept, out, []data.Notice{downsampleNotice(len(rows), len(kept), "rows", algo)}
+}
+
+// fillCells fills the unset cells between the first and last sample of a
+// series with the previous value or a linear interpolation over time.
+func fillCells(cells []wideCell, rows []time.Time, fill string) {
+	if fill != alignFillPrevious && fill != alignFillLinear {
+		return
+	}
+
+	prev := -1
+	for i, c := range cells {
+		if !c.set {
+			continue
+		}
+		if prev >= 0 && i-prev > 1 && !cells[prev].null && !c.null {
+			for j := prev + 1; j < i; j++ {
+				cells[j] = cells[prev]
+				if fill == alignFillLinear {
+					frac := float64(rows[j].Sub(rows[prev])) / float64(rows[i].Sub(rows[prev]))
+					cells[j].value += frac * (c.value - cells[prev].value)
+					cells[j].quality = min(cells[prev].quality, c.quality)
+				}
+			}
+		}
+		prev = i
+	}
+
+	// Carry the last value forward to the end of the frame.
+	if fill == alignFillPrevious && prev >= 0 && !cells[prev].null {
+		for j := prev + 1; j < len(cells); j++ {
+			cells[j] = cells[prev]
+		}
+	}
+}
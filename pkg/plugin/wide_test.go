@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWideFrame(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := &series{name: "a", points: []LiveValueTimeseries{
+		{Timestamp: t0, Value: 0},
+		{Timestamp: t0.Add(10 * time.Second), Value: 10},
+	}}
+	b := &series{name: "b", points: []LiveValueTimeseries{
+		{Timestamp: t0.Add(200 * time.Millisecond), Value: 1},
+		{Timestamp: t0.Add(5 * time.Second), Value: 2},
+	}}
+
+	frame := wideFrame([]*series{a, b}, time.Second, alignFillLinear, false, downsampleNone, 0)
+	if rows, _ := frame.RowLen(); rows != 3 {
+		t.Fatalf("got %d rows, want 3", rows)
+	}
+	if len(frame.Fields) != 3 {
+		t.Fatalf("got %d fields, want 3", len(frame.Fields))
+	}
+	if v, _ := frame.Fields[1].ConcreteAt(1); v != 5.0 {
+		t.Errorf("interpolated a = %v, want 5", v)
+	}
+	if v, ok := frame.Fields[2].ConcreteAt(2); ok {
+		t.Errorf("b after its last sample = %v, want null", v)
+	}
+
+	frame = wideFrame([]*series{a, b}, time.Second, alignFillPrevious, false, downsampleNone, 0)
+	if v, _ := frame.Fields[2].ConcreteAt(2); v != 2.0 {
+		t.Errorf("previous b = %v, want 2", v)
+	}
+}
+
+func TestWideFrameDownsample(t *testing.T) {
+	// b logs 100ms after a, within the alignment tolerance, with a
+	// different shape, so that downsampling each alone picks other times.
+	a := &series{name: "a", points: testPoints(1000, func(i int) float64 { return math.Sin(float64(i) / 10) })}
+	b := &series{name: "b", points: testPoints(1000, func(i int) float64 { return math.Cos(float64(i) / 37) })}
+	for i := range b.points {
+		b.points[i].Timestamp = b.points[i].Timestamp.Add(100 * time.Millisecond)
+	}
+
+	frame := wideFrame([]*series{a, b}, 500*time.Millisecond, alignFillNull, false, downsampleLTTB, 100)
+	rows, _ := frame.RowLen()
+	if rows > 100 || rows < 50 {
+		t.Fatalf("got %d rows, want 50 to 100", rows)
+	}
+	for _, f := range frame.Fields[1:] {
+		for i := 0; i < rows; i++ {
+			if _, ok := f.ConcreteAt(i); !ok {
+				t.Fatalf("%s: row %d is null", f.Name, i)
+			}
+		}
+	}
+	if frame.Meta == nil || len(frame.Meta.Notices) != 1 {
+		t.Error("expected a single downsampling notice")
+	}
+}
@@ -1,17 +1,48 @@
 import React, { ChangeEvent } from 'react';
-import { InlineField, SecretInput } from '@grafana/ui';
+import { InlineField, Input, SecretInput } from '@grafana/ui';
 import { DataSourcePluginOptionsEditorProps } from '@grafana/data';
 import { MyDataSourceOptions, MySecureJsonData } from '../types';
 
 interface Props extends DataSourcePluginOptionsEditorProps<MyDataSourceOptions, MySecureJsonData> {}
 
+const labelWidth = 24;
+
+// Empty number inputs clear the setting so the backend default applies.
+const numberValue = (event: ChangeEvent<HTMLInputElement>) =>
+  event.target.value === '' ? undefined : Number(event.target.value);
+
 export function ConfigEditor(props: Props) {
   const { onOptionsChange, options } = props;
-  const { secureJsonFields, secureJsonData } = options;
-
+  const { jsonData, secureJsonFields, secureJsonData } = options;
 
+  const onBaseUrlChange = (event: ChangeEvent<HTMLInputElement>) => {
+    onOptionsChange({
+      ...options,
+      jsonData: {
+        ...jsonData,
+        baseUrl: event.target.value,
+      },
+    });
+  };
 
+  const onJsonDataChange = <K extends keyof MyDataSourceOptions>(key: K, value: MyDataSourceOptions[K]) => {
+    onOptionsChange({
+      ...options,
+      jsonData: {
+        ...jsonData,
+        [key]: value,
+      },
+    });
+  };
 
+  // Parsed on blur, so that a half-typed list is not rewritten while typing.
+  const onRetryStatusesBlur = (event: React.FocusEvent<HTMLInputElement>) => {
+    const statuses = event.target.value
+      .split(',')
+      .map((s) => parseInt(s.trim(), 10))
+      .filter((n) => !isNaN(n));
+    onJsonDataChange('retryStatuses', statuses.length > 0 ? statuses : undefined);
+  };
 
   // Secure field (only sent to the backend)
   const onAPIKeyChange = (event: ChangeEvent<HTMLInputElement>) => {
@@ -39,22 +70,21 @@ export function ConfigEditor(props: Props) {
 
   return (
     <>
-     
-         {/* <InlineField
+      <InlineField
         label="Base URL"
-        labelWidth={14}
-        tooltip="Base API URL used for queries"
+        labelWidth={labelWidth}
+        tooltip="InView API URL. Leave empty to use the InView cloud."
       >
         <Input
+          id="config-editor-base-url"
           width={40}
           value={jsonData.baseUrl || ''}
           onChange={onBaseUrlChange}
-          placeholder="https://my-api.example.com"
+          placeholder="https://cloud.oilfield-monitor.com"
         />
-      </InlineField> */}
-
+      </InlineField>
 
-      <InlineField label="API Key" labelWidth={14} interactive tooltip={'Secure json field (backend only)'}>
+      <InlineField label="API Key" labelWidth={labelWidth} interactive tooltip={'Secure json field (backend only)'}>
         <SecretInput
           required
           id="config-editor-api-key"
@@ -66,6 +96,192 @@ export function ConfigEditor(props: Props) {
           onChange={onAPIKeyChange}
         />
       </InlineField>
+
+      <InlineField
+        label="Timezone"
+        labelWidth={labelWidth}
+        tooltip="IANA timezone the InView server logs in, e.g. Europe/Vienna. Timestamps without an offset are read in it and aggregation buckets are aligned to it."
+      >
+        <Input
+          id="config-editor-timezone"
+          width={40}
+          value={jsonData.timezone || ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('timezone', e.target.value)}
+          placeholder="UTC"
+        />
+      </InlineField>
+
+      <InlineField label="Timeout" labelWidth={labelWidth} tooltip="Overall request timeout in seconds.">
+        <Input
+          id="config-editor-timeout"
+          type="number"
+          min={1}
+          width={40}
+          value={jsonData.timeout ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('timeout', numberValue(e))}
+          placeholder="30"
+        />
+      </InlineField>
+
+      <InlineField label="Dial timeout" labelWidth={labelWidth} tooltip="TCP connect timeout in seconds.">
+        <Input
+          id="config-editor-dial-timeout"
+          type="number"
+          min={1}
+          width={40}
+          value={jsonData.dialTimeout ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('dialTimeout', numberValue(e))}
+          placeholder="10"
+        />
+      </InlineField>
+
+      <InlineField label="TLS handshake timeout" labelWidth={labelWidth} tooltip="TLS handshake timeout in seconds.">
+        <Input
+          id="config-editor-tls-handshake-timeout"
+          type="number"
+          min={1}
+          width={40}
+          value={jsonData.tlsHandshakeTimeout ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('tlsHandshakeTimeout', numberValue(e))}
+          placeholder="10"
+        />
+      </InlineField>
+
+      <InlineField
+        label="Retry attempts"
+        labelWidth={labelWidth}
+        tooltip="Total number of attempts per InView request. 1 disables retries."
+      >
+        <Input
+          id="config-editor-retry-max-attempts"
+          type="number"
+          min={1}
+          width={40}
+          value={jsonData.retryMaxAttempts ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('retryMaxAttempts', numberValue(e))}
+          placeholder="3"
+        />
+      </InlineField>
+
+      <InlineField
+        label="Retry delay (ms)"
+        labelWidth={labelWidth}
+        tooltip="Delay before the first retry, doubled for each further retry."
+      >
+        <Input
+          id="config-editor-retry-base-delay"
+          type="number"
+          min={1}
+          width={40}
+          value={jsonData.retryBaseDelayMs ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('retryBaseDelayMs', numberValue(e))}
+          placeholder="500"
+        />
+      </InlineField>
+
+      <InlineField
+        label="Retry jitter"
+        labelWidth={labelWidth}
+        tooltip="Randomizes retry delays by up to this fraction, between 0 and 1."
+      >
+        <Input
+          id="config-editor-retry-jitter"
+          type="number"
+          min={0}
+          max={1}
+          step={0.1}
+          width={40}
+          value={jsonData.retryJitter ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('retryJitter', numberValue(e))}
+          placeholder="0.2"
+        />
+      </InlineField>
+
+      <InlineField
+        label="Retry statuses"
+        labelWidth={labelWidth}
+        tooltip="Comma-separated HTTP statuses that are retried."
+      >
+        <Input
+          id="config-editor-retry-statuses"
+          width={40}
+          defaultValue={jsonData.retryStatuses?.join(', ') ?? ''}
+          onBlur={onRetryStatusesBlur}
+          placeholder="429, 502, 503, 504"
+        />
+      </InlineField>
+
+      <InlineField
+        label="Max rows"
+        labelWidth={labelWidth}
+        tooltip="Caps alarm and event queries that fetch all pages."
+      >
+        <Input
+          id="config-editor-max-rows"
+          type="number"
+          min={1}
+          width={40}
+          value={jsonData.maxRows ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('maxRows', numberValue(e))}
+          placeholder="10000"
+        />
+      </InlineField>
+
+      <InlineField
+        label="Max concurrent queries"
+        labelWidth={labelWidth}
+        tooltip="Queries this datasource runs at once."
+      >
+        <Input
+          id="config-editor-max-concurrent-queries"
+          type="number"
+          min={1}
+          width={40}
+          value={jsonData.maxConcurrentQueries ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('maxConcurrentQueries', numberValue(e))}
+          placeholder="8"
+        />
+      </InlineField>
+
+      <InlineField
+        label="History chunk"
+        labelWidth={labelWidth}
+        tooltip="Window size long history queries are split into, e.g. 12h or 1d."
+      >
+        <Input
+          id="config-editor-history-chunk"
+          width={40}
+          value={jsonData.historyChunk || ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('historyChunk', e.target.value)}
+          placeholder="1d"
+        />
+      </InlineField>
+
+      <InlineField label="History chunk concurrency" labelWidth={labelWidth} tooltip="History windows fetched at once.">
+        <Input
+          id="config-editor-history-chunk-concurrency"
+          type="number"
+          min={1}
+          width={40}
+          value={jsonData.historyChunkConcurrency ?? ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('historyChunkConcurrency', numberValue(e))}
+          placeholder="4"
+        />
+      </InlineField>
+
+      <InlineField
+        label="Catalog cache TTL"
+        labelWidth={labelWidth}
+        tooltip="How long the variable catalog, which supplies units and display names, is cached, e.g. 10m."
+      >
+        <Input
+          id="config-editor-catalog-cache-ttl"
+          width={40}
+          value={jsonData.catalogCacheTtl || ''}
+          onChange={(e: ChangeEvent<HTMLInputElement>) => onJsonDataChange('catalogCacheTtl', e.target.value)}
+          placeholder="10m"
+        />
+      </InlineField>
     </>
   );
 }
@@ -1,5 +1,5 @@
 import React, { useEffect, useState } from 'react';
-import { Stack, InlineField, Input, Select, Button, RadioButtonGroup } from '@grafana/ui';
+import { Stack, InlineField, InlineSwitch, Input, Select, Button, RadioButtonGroup } from '@grafana/ui';
 import { QueryEditorProps } from '@grafana/data';
 import { DataSource } from '../datasource';
 import { MyDataSourceOptions, MyQuery } from '../types';
@@ -10,6 +10,86 @@ import { ConnectionSelector, ConnectionType } from './ConnectionSelector';
 
 type Props = QueryEditorProps<DataSource, MyQuery, MyDataSourceOptions>;
 
+// Per-query options edited below. Left unset, the backend applies its
+// defaults.
+type QueryOptions = Pick<
+  MyQuery,
+  | 'downsample'
+  | 'aggregation'
+  | 'bucketSize'
+  | 'transform'
+  | 'transformUnit'
+  | 'counterMax'
+  | 'qualityMode'
+  | 'showQuality'
+  | 'outputFormat'
+  | 'alignTolerance'
+  | 'alignFill'
+  | 'gapThreshold'
+  | 'gapFactor'
+  | 'gapFill'
+  | 'gapFillValue'
+  | 'expression'
+  | 'expressionName'
+  | 'aliases'
+  | 'currentLookback'
+  | 'operators'
+  | 'floodThreshold'
+  | 'chatterSeconds'
+  | 'staleAfter'
+  | 'maxRows'
+>;
+
+const queryOptions = (query: MyQuery): QueryOptions => ({
+  downsample: query.downsample,
+  aggregation: query.aggregation,
+  bucketSize: query.bucketSize,
+  transform: query.transform,
+  transformUnit: query.transformUnit,
+  counterMax: query.counterMax,
+  qualityMode: query.qualityMode,
+  showQuality: query.showQuality,
+  outputFormat: query.outputFormat,
+  alignTolerance: query.alignTolerance,
+  alignFill: query.alignFill,
+  gapThreshold: query.gapThreshold,
+  gapFactor: query.gapFactor,
+  gapFill: query.gapFill,
+  gapFillValue: query.gapFillValue,
+  expression: query.expression,
+  expressionName: query.expressionName,
+  aliases: query.aliases,
+  currentLookback: query.currentLookback,
+  operators: query.operators,
+  floodThreshold: query.floodThreshold,
+  chatterSeconds: query.chatterSeconds,
+  staleAfter: query.staleAfter,
+  maxRows: query.maxRows,
+});
+
+// Text and number options are read on blur, so the query does not run on
+// every keystroke. Empty inputs unset the option.
+const textValue = (e: React.FocusEvent<HTMLInputElement>) => e.currentTarget.value.trim() || undefined;
+const numberValue = (e: React.FocusEvent<HTMLInputElement>) =>
+  e.currentTarget.value === '' ? undefined : Number(e.currentTarget.value);
+
+// Aliases are edited as "name=id" pairs separated by commas.
+const formatAliases = (aliases?: Record<string, number>) =>
+  Object.entries(aliases ?? {})
+    .map(([name, id]) => `${name}=${id}`)
+    .join(', ');
+
+const parseAliases = (text: string): Record<string, number> | undefined => {
+  const aliases: Record<string, number> = {};
+  for (const pair of text.split(',')) {
+    const [name, id] = pair.split('=').map((s) => s.trim());
+    if (name && id && !isNaN(parseInt(id, 10))) {
+      aliases[name] = parseInt(id, 10);
+    }
+  }
+  return Object.keys(aliases).length > 0 ? aliases : undefined;
+};
+
 export function QueryEditor({ datasource, query, onChange, onRunQuery }: Props) {
   const [connections, setConnections] = useState<ConnectionType[]>(query.connections ?? []);
   const [selectedConnId, setSelectedConnId] = useState<number | null>(query.connectionId ?? null);
@@ -18,13 +98,33 @@ export function QueryEditor({ datasource, query, onChange, onRunQuery }: Props)
   const [selectedVariables, setSelectedVariables] = useState<VariableType[]>(query.variables ?? []);
   const [variables, setVariables] = useState<VariableType[]>([]);
 
-  const [type, setType] = useState<'Alarm' | 'Event' | 'Live'>(
-    query.isAlarm ? 'Alarm' : query.isEvent ? 'Event' : query.isLive ? 'Live' : 'Live'
+  const [type, setType] = useState<'Alarm' | 'Event' | 'Live' | 'Statistics' | 'Current' | 'AlarmKPI'>(
+    query.isAlarm
+      ? 'Alarm'
+      : query.isEvent
+      ? 'Event'
+      : query.isStatistics
+      ? 'Statistics'
+      : query.isCurrent
+      ? 'Current'
+      : query.isAlarmKpi
+      ? 'AlarmKPI'
+      : 'Live'
   );
   const [prefix, setPrefix] = useState(query.prefix ?? '');
   const [opcTags, setOpcTags] = useState(query.opcTags ?? '');
   const [pageIndex, setPageIndex] = useState(query.pageIndex ?? 0);
   const [pageSize, setPageSize] = useState(query.pageSize ?? 20);
+  const [allPages, setAllPages] = useState(query.allPages ?? false);
+  const [activeOnly, setActiveOnly] = useState(query.activeOnly ?? false);
+  const [options, setOptions] = useState<QueryOptions>(queryOptions(query));
+
+  const setOption = <K extends keyof QueryOptions>(key: K, value: QueryOptions[K]) => {
+    setOptions((prev) => ({ ...prev, [key]: value }));
+  };
+
+  // Only alarms and events are fetched page by page.
+  const paged = type === 'Alarm' || type === 'Event';
 
   useEffect(() => {
     onChange({
@@ -41,17 +141,23 @@ export function QueryEditor({ datasource, query, onChange, onRunQuery }: Props)
       isAlarm: type === 'Alarm',
       isEvent: type === 'Event',
       isLive: type === 'Live',
+      isStatistics: type === 'Statistics',
+      isCurrent: type === 'Current',
+      isAlarmKpi: type === 'AlarmKPI',
       prefix : prefix,
       opcTags : opcTags,
       pageIndex : pageIndex,
       pageSize : pageSize,
+      allPages : allPages,
+      activeOnly : activeOnly,
+      ...options,
       variables : selectedVariables
     };
 
   onChange({ ...updatedQuery }); 
   
   onRunQuery();
-  }, [type, prefix, opcTags, pageIndex, pageSize,selectedVariables]);
+  }, [type, prefix, opcTags, pageIndex, pageSize, allPages, activeOnly, options, selectedVariables]);
 
   useEffect(() => {
     if (connections.length === 0) {
@@ -232,17 +338,364 @@ const onTextVariableChange = async (value: string) => {
 
       {/* Alarm/Event Type */}
       <InlineField label="Type" labelWidth={14}>
-        <RadioButtonGroup<'Alarm' | 'Event' | 'Live'>
+        <RadioButtonGroup<'Alarm' | 'Event' | 'Live' | 'Statistics' | 'Current' | 'AlarmKPI'>
           options={[
             { label: 'Live', value: 'Live' },
             { label: 'Alarm', value: 'Alarm' },
             { label: 'Event', value: 'Event' },
+            { label: 'Statistics', value: 'Statistics' },
+            { label: 'Current value', value: 'Current' },
+            { label: 'Alarm KPIs', value: 'AlarmKPI' },
           ]}
           value={type}
           onChange={(v) => setType(v)}
         />
       </InlineField>
 
+      {type === 'Alarm' && (
+        <InlineField label="Active only" labelWidth={14} tooltip="Only return alarms that have not cleared">
+          <InlineSwitch value={activeOnly} onChange={(e) => setActiveOnly(e.currentTarget.checked)} />
+        </InlineField>
+      )}
+
+      {type === 'Live' && (
+        <>
+          {/* Expression */}
+          <InlineField
+            label="Expression"
+            labelWidth={22}
+            tooltip="Derives a series from several variables, e.g. $12 - $13 or max(${Tank 1}, ${Tank 2}) * 24. Reference variables by ID, alias or name."
+          >
+            <Input
+              defaultValue={options.expression ?? ''}
+              onBlur={(e) => setOption('expression', textValue(e))}
+              placeholder="$12 - $13"
+              width={50}
+            />
+          </InlineField>
+
+          <Stack direction="row" gap={1}>
+            <InlineField label="Expression name" labelWidth={22}>
+              <Input
+                defaultValue={options.expressionName ?? ''}
+                onBlur={(e) => setOption('expressionName', textValue(e))}
+                placeholder="Expression"
+                width={20}
+              />
+            </InlineField>
+
+            <InlineField label="Aliases" labelWidth={10} tooltip="Names for variable IDs, e.g. level=12, flow=13">
+              <Input
+                defaultValue={formatAliases(options.aliases)}
+                onBlur={(e) => setOption('aliases', parseAliases(e.currentTarget.value))}
+                placeholder="level=12, flow=13"
+                width={30}
+              />
+            </InlineField>
+          </Stack>
+
+          {/* Aggregation */}
+          <Stack direction="row" gap={1}>
+            <InlineField label="Aggregation" labelWidth={22} tooltip="Reduces the samples to one value per time bucket">
+              <Select<NonNullable<MyQuery['aggregation']>>
+                options={[
+                  { label: 'Average', value: 'avg' },
+                  { label: 'Time-weighted average', value: 'twavg' },
+                  { label: 'Min', value: 'min' },
+                  { label: 'Max', value: 'max' },
+                  { label: 'Sum', value: 'sum' },
+                  { label: 'Count', value: 'count' },
+                  { label: 'First', value: 'first' },
+                  { label: 'Last', value: 'last' },
+                  { label: 'Range', value: 'range' },
+                  { label: 'Std. deviation', value: 'stddev' },
+                ]}
+                value={options.aggregation}
+                onChange={(v) => setOption('aggregation', v?.value)}
+                placeholder="Raw samples"
+                isClearable
+                width={24}
+              />
+            </InlineField>
+
+            <InlineField
+              label="Bucket"
+              labelWidth={10}
+              tooltip="Aggregation and delta bucket, e.g. 1h. Empty uses the panel interval."
+            >
+              <Input
+                defaultValue={options.bucketSize ?? ''}
+                onBlur={(e) => setOption('bucketSize', textValue(e))}
+                placeholder="auto"
+                width={12}
+              />
+            </InlineField>
+          </Stack>
+
+          {/* Transform */}
+          <Stack direction="row" gap={1}>
+            <InlineField label="Transform" labelWidth={22}>
+              <Select<NonNullable<MyQuery['transform']>>
+                options={[
+                  { label: 'Derivative', value: 'derivative' },
+                  { label: 'Rate', value: 'rate', description: 'Counter-aware, never negative' },
+                  { label: 'Integral', value: 'integral' },
+                  { label: 'Delta', value: 'delta', description: 'Counter increase per bucket' },
+                ]}
+                value={options.transform}
+                onChange={(v) => setOption('transform', v?.value)}
+                placeholder="None"
+                isClearable
+                width={24}
+              />
+            </InlineField>
+
+            <InlineField label="Per" labelWidth={10}>
+              <Select<NonNullable<MyQuery['transformUnit']>>
+                options={[
+                  { label: 'second', value: 's' },
+                  { label: 'minute', value: 'm' },
+                  { label: 'hour', value: 'h' },
+                ]}
+                value={options.transformUnit}
+                onChange={(v) => setOption('transformUnit', v?.value)}
+                placeholder="second"
+                isClearable
+                width={12}
+              />
+            </InlineField>
+
+            <InlineField
+              label="Counter max"
+              labelWidth={14}
+              tooltip="Value the counter rolls over at. Empty treats drops as resets to zero."
+            >
+              <Input
+                type="number"
+                defaultValue={options.counterMax ?? ''}
+                onBlur={(e) => setOption('counterMax', numberValue(e))}
+                width={14}
+              />
+            </InlineField>
+          </Stack>
+
+          {/* Gaps */}
+          <Stack direction="row" gap={1}>
+            <InlineField label="Gap threshold" labelWidth={22} tooltip="Holes longer than this, e.g. 5m, are gaps">
+              <Input
+                defaultValue={options.gapThreshold ?? ''}
+                onBlur={(e) => setOption('gapThreshold', textValue(e))}
+                placeholder="off"
+                width={12}
+              />
+            </InlineField>
+
+            <InlineField
+              label="or factor"
+              labelWidth={10}
+              tooltip="Holes longer than this multiple of the logging interval are gaps, when no threshold is set"
+            >
+              <Input
+                type="number"
+                min={1}
+                defaultValue={options.gapFactor ?? ''}
+                onBlur={(e) => setOption('gapFactor', numberValue(e))}
+                placeholder="off"
+                width={10}
+              />
+            </InlineField>
+
+            <InlineField label="Fill" labelWidth={6}>
+              <Select<NonNullable<MyQuery['gapFill']>>
+                options={[
+                  { label: 'Null', value: 'null' },
+                  { label: 'Previous', value: 'previous' },
+                  { label: 'Linear', value: 'linear' },
+                  { label: 'Zero', value: 'zero' },
+                  { label: 'Constant', value: 'constant' },
+                ]}
+                value={options.gapFill}
+                onChange={(v) => setOption('gapFill', v?.value)}
+                placeholder="Null"
+                isClearable
+                width={14}
+              />
+            </InlineField>
+
+            {options.gapFill === 'constant' && (
+              <InlineField label="Value" labelWidth={8}>
+                <Input
+                  type="number"
+                  defaultValue={options.gapFillValue ?? ''}
+                  onBlur={(e) => setOption('gapFillValue', numberValue(e))}
+                  width={10}
+                />
+              </InlineField>
+            )}
+          </Stack>
+
+          {/* Output */}
+          <Stack direction="row" gap={1}>
+            <InlineField label="Output" labelWidth={22}>
+              <RadioButtonGroup<NonNullable<MyQuery['outputFormat']>>
+                options={[
+                  { label: 'Series', value: 'series' },
+                  { label: 'Wide', value: 'wide' },
+                ]}
+                value={options.outputFormat ?? 'series'}
+                onChange={(v) => setOption('outputFormat', v)}
+              />
+            </InlineField>
+
+            <InlineField
+              label="Align within"
+              labelWidth={14}
+              tooltip="Timestamps this close together, e.g. 1s, share a row of the wide frame and of expressions"
+            >
+              <Input
+                defaultValue={options.alignTolerance ?? ''}
+                onBlur={(e) => setOption('alignTolerance', textValue(e))}
+                placeholder="exact"
+                width={10}
+              />
+            </InlineField>
+
+            <InlineField
+              label="Align fill"
+              labelWidth={12}
+              tooltip="Fills the rows a variable has no sample for. Expressions hold the previous value by default."
+            >
+              <Select<NonNullable<MyQuery['alignFill']>>
+                options={[
+                  { label: 'Null', value: 'null' },
+                  { label: 'Previous', value: 'previous' },
+                  { label: 'Linear', value: 'linear' },
+                ]}
+                value={options.alignFill}
+                onChange={(v) => setOption('alignFill', v?.value)}
+                placeholder="Default"
+                isClearable
+                width={14}
+              />
+            </InlineField>
+          </Stack>
+
+          <InlineField
+            label="Downsample"
+            labelWidth={22}
+            tooltip="Reduces series longer than the panel's max data points"
+          >
+            <Select<NonNullable<MyQuery['downsample']>>
+              options={[
+                { label: 'LTTB', value: 'lttb', description: 'Keeps the visual shape' },
+                { label: 'Min/max', value: 'minmax', description: 'Keeps spikes' },
+                { label: 'First/last', value: 'firstlast' },
+                { label: 'None', value: 'none' },
+              ]}
+              value={options.downsample}
+              onChange={(v) => setOption('downsample', v?.value)}
+              placeholder="LTTB"
+              isClearable
+              width={24}
+            />
+          </InlineField>
+        </>
+      )}
+
+      {(type === 'Live' || type === 'Statistics') && (
+        <Stack direction="row" gap={1}>
+          <InlineField label="Quality" labelWidth={22} tooltip="What happens to samples whose quality is not good">
+            <RadioButtonGroup<NonNullable<MyQuery['qualityMode']>>
+              options={[
+                { label: 'Keep', value: 'all' },
+                { label: 'Exclude', value: 'exclude' },
+                { label: 'Null', value: 'null' },
+              ]}
+              value={options.qualityMode ?? 'all'}
+              onChange={(v) => setOption('qualityMode', v)}
+            />
+          </InlineField>
+
+          {type === 'Live' && (
+            <InlineField label="Show quality" labelWidth={14} tooltip="Adds a Good/Uncertain/Bad quality field">
+              <InlineSwitch
+                value={options.showQuality ?? false}
+                onChange={(e) => setOption('showQuality', e.currentTarget.checked)}
+              />
+            </InlineField>
+          )}
+        </Stack>
+      )}
+
+      {type === 'Current' && (
+        <InlineField label="Lookback" labelWidth={22} tooltip="How far back to look for the latest sample, e.g. 1h">
+          <Input
+            defaultValue={options.currentLookback ?? ''}
+            onBlur={(e) => setOption('currentLookback', textValue(e))}
+            placeholder="24h"
+            width={12}
+          />
+        </InlineField>
+      )}
+
+      {type === 'AlarmKPI' && (
+        <Stack direction="row" gap={1}>
+          <InlineField label="Operators" labelWidth={22} tooltip="Operators sharing the alarm load">
+            <Input
+              type="number"
+              min={1}
+              defaultValue={options.operators ?? ''}
+              onBlur={(e) => setOption('operators', numberValue(e))}
+              placeholder="1"
+              width={10}
+            />
+          </InlineField>
+
+          <InlineField
+            label="Flood above"
+            labelWidth={14}
+            tooltip="Alarms per operator per 10 minutes above which the operator is flooded"
+          >
+            <Input
+              type="number"
+              min={1}
+              defaultValue={options.floodThreshold ?? ''}
+              onBlur={(e) => setOption('floodThreshold', numberValue(e))}
+              placeholder="10"
+              width={10}
+            />
+          </InlineField>
+
+          <InlineField
+            label="Chatter within (s)"
+            labelWidth={18}
+            tooltip="Re-activations this soon after clearing count as chattering"
+          >
+            <Input
+              type="number"
+              min={1}
+              defaultValue={options.chatterSeconds ?? ''}
+              onBlur={(e) => setOption('chatterSeconds', numberValue(e))}
+              placeholder="60"
+              width={10}
+            />
+          </InlineField>
+
+          <InlineField
+            label="Stale after"
+            labelWidth={12}
+            tooltip="Alarms standing longer than this, e.g. 24h, are stale"
+          >
+            <Input
+              defaultValue={options.staleAfter ?? ''}
+              onBlur={(e) => setOption('staleAfter', textValue(e))}
+              placeholder="24h"
+              width={10}
+            />
+          </InlineField>
+        </Stack>
+      )}
+
       {/* Prefix */}
       <InlineField label="Prefix" labelWidth={22}>
         <Input
@@ -263,55 +716,84 @@ const onTextVariableChange = async (value: string) => {
         />
       </InlineField>
 
-      {/* Pagination */}
-      <Stack direction="row" gap={1}>
-        <Button
-          variant="secondary"
-          icon="angle-left"
-          disabled={pageIndex === 0}
-          onClick={() => {
-            setPageIndex((prev) => Math.max(prev - 1, 0));
-          }}
-        />
-
-        <InlineField label="Page" labelWidth={6}>
-          <Input
-            type="number"
-            value={pageIndex + 1}
-            min={1}
-            onChange={(e) => {
-              const newPage = Number(e.currentTarget.value) - 1;
-              setPageIndex(newPage >= 0 ? newPage : 0);
-            }}
-            width={12}
-          />
-        </InlineField>
-
-        <Button
-          variant="secondary"
-          icon="angle-right"
-          onClick={() => {
-            setPageIndex((prev) => prev + 1);
-          }}
-        />
-
-        <InlineField label="Rows" labelWidth={6}>
-          <Select
-            options={[
-              { label: '10', value: 10 },
-              { label: '20', value: 20 },
-              { label: '50', value: 50 },
-              { label: '100', value: 100 },
-            ]}
-            value={pageSize}
-            onChange={(v) => {
-              setPageSize(v.value!);
-              setPageIndex(0);
-            }}
-            width={12}
-          />
-        </InlineField>
-      </Stack>
+      {/* Pagination: alarm KPIs always walk every page up to the row cap */}
+      {(paged || type === 'AlarmKPI') && (
+        <Stack direction="row" gap={1}>
+          {paged && (
+            <InlineField label="All pages" labelWidth={12} tooltip="Fetch every page of the time range">
+              <InlineSwitch value={allPages} onChange={(e) => setAllPages(e.currentTarget.checked)} />
+            </InlineField>
+          )}
+
+          {(allPages || !paged) && (
+            <InlineField
+              label="Max rows"
+              labelWidth={10}
+              tooltip="Caps the rows fetched. Empty uses the datasource limit."
+            >
+              <Input
+                type="number"
+                min={1}
+                defaultValue={options.maxRows ?? ''}
+                onBlur={(e) => setOption('maxRows', numberValue(e))}
+                width={12}
+              />
+            </InlineField>
+          )}
+
+          {paged && (
+            <>
+              <Button
+                variant="secondary"
+                icon="angle-left"
+                disabled={allPages || pageIndex === 0}
+                onClick={() => {
+                  setPageIndex((prev) => Math.max(prev - 1, 0));
+                }}
+              />
+
+              <InlineField label="Page" labelWidth={6}>
+                <Input
+                  type="number"
+                  value={pageIndex + 1}
+                  min={1}
+                  onChange={(e) => {
+                    const newPage = Number(e.currentTarget.value) - 1;
+                    setPageIndex(newPage >= 0 ? newPage : 0);
+                  }}
+                  width={12}
+                />
+              </InlineField>
+
+              <Button
+                variant="secondary"
+                icon="angle-right"
+                disabled={allPages}
+                onClick={() => {
+                  setPageIndex((prev) => prev + 1);
+                }}
+              />
+
+              <InlineField label="Rows" labelWidth={6}>
+                <Select
+                  options={[
+                    { label: '10', value: 10 },
+                    { label: '20', value: 20 },
+                    { label: '50', value: 50 },
+                    { label: '100', value: 100 },
+                  ]}
+                  value={pageSize}
+                  onChange={(v) => {
+                    setPageSize(v.value!);
+                    setPageIndex(0);
+                  }}
+                  width={12}
+                />
+              </InlineField>
+            </>
+          )}
+        </Stack>
+      )}
     </Stack>
   );
 }
@@ -14,10 +14,39 @@ export interface MyQuery extends DataQuery {
   isLive : boolean;
   isAlarm : boolean;
   isEvent : boolean;
+  isStatistics?: boolean;
+  isCurrent?: boolean;
+  currentLookback?: string;
+  isAlarmKpi?: boolean;
+  operators?: number;
+  floodThreshold?: number;
+  chatterSeconds?: number;
+  staleAfter?: string;
   prefix: string;
   opcTags: string;
   pageIndex: number;
   pageSize: number;
+  allPages?: boolean;
+  activeOnly?: boolean;
+  maxRows?: number;
+  downsample?: 'lttb' | 'minmax' | 'firstlast' | 'none';
+  aggregation?: 'avg' | 'twavg' | 'min' | 'max' | 'sum' | 'count' | 'first' | 'last' | 'range' | 'stddev';
+  bucketSize?: string;
+  transform?: 'derivative' | 'rate' | 'integral' | 'delta';
+  transformUnit?: 's' | 'm' | 'h';
+  counterMax?: number;
+  qualityMode?: 'all' | 'exclude' | 'null';
+  showQuality?: boolean;
+  outputFormat?: 'series' | 'wide';
+  alignTolerance?: string;
+  alignFill?: 'null' | 'previous' | 'linear';
+  gapThreshold?: string;
+  gapFactor?: number;
+  gapFill?: 'null' | 'previous' | 'linear' | 'zero' | 'constant';
+  gapFillValue?: number;
+  expression?: string;
+  expressionName?: string;
+  aliases?: Record<string, number>;
   connections?: ConnectionType[];
 
 
@@ -45,7 +74,20 @@ export interface DataSourceResponse {
  */
 export interface MyDataSourceOptions extends DataSourceJsonData {
   path?: string;
-  baseUrl?: string;   // ✅ Add this
+  baseUrl?: string;
+  timeout?: number;
+  dialTimeout?: number;
+  tlsHandshakeTimeout?: number;
+  retryMaxAttempts?: number;
+  retryBaseDelayMs?: number;
+  retryJitter?: number;
+  retryStatuses?: number[];
+  maxRows?: number;
+  maxConcurrentQueries?: number;
+  historyChunk?: string;
+  historyChunkConcurrency?: number;
+  catalogCacheTtl?: string;
+  timezone?: string;
 }
 
 /**